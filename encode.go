@@ -15,8 +15,8 @@ func Encode(cal Calendar, w io.Writer) error {
 }
 
 // NewEncoder returns a new encode.Encoder that writes to w.
-func NewEncoder(w io.Writer) *encode.Encoder {
-	return encode.NewEncoder(w)
+func NewEncoder(w io.Writer, opts ...encode.Option) *encode.Encoder {
+	return encode.NewEncoder(w, opts...)
 }
 
 // Marshal returns the encoded bytes of cal.
@@ -27,3 +27,18 @@ func Marshal(cal Calendar) ([]byte, error) {
 	}
 	return buf.Bytes(), nil
 }
+
+// MarshalText implements encoding.TextMarshaler. It returns the encoded bytes of cal.
+func (cal Calendar) MarshalText() ([]byte, error) {
+	return Marshal(cal)
+}
+
+// ContentType returns the media type of the encoded cal, including the
+// "method" parameter if cal has a METHOD (https://tools.ietf.org/html/rfc5545#section-8.1),
+// e.g. for the Content-Type header of an HTTP response that serves cal.
+func (cal Calendar) ContentType() string {
+	if cal.Method == "" {
+		return "text/calendar; charset=utf-8"
+	}
+	return "text/calendar; charset=utf-8; method=" + cal.Method
+}
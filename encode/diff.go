@@ -0,0 +1,64 @@
+package encode
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bounoable/ical/parse"
+)
+
+// EncodeEventDiff writes the content lines of the properties of newEvt that
+// have changed since oldEvt, e.g. for PATCH-like updates of a CalDAV resource.
+// A property has changed if oldEvt has no property with the same name, value
+// and parameters. Properties that have been removed from oldEvt are not written,
+// because a content line cannot express a removal. Every content line is
+// terminated by a CRLF; nothing is written if no property has changed.
+func EncodeEventDiff(oldEvt, newEvt parse.Event, w io.Writer) error {
+	oldProps := eventProperties(oldEvt)
+
+	for _, prop := range eventProperties(newEvt) {
+		if containsProperty(oldProps, prop) {
+			continue
+		}
+
+		line, err := contentLine(prop, false)
+		if err != nil {
+			return fmt.Errorf("encode property: %w", err)
+		}
+
+		if _, err = io.WriteString(w, line+"\r\n"); err != nil {
+			return fmt.Errorf("write string: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func containsProperty(props []parse.Property, prop parse.Property) bool {
+	for _, p := range props {
+		if p.Name == prop.Name && p.Value == prop.Value && paramsEqual(p.Params, prop.Params) {
+			return true
+		}
+	}
+	return false
+}
+
+func paramsEqual(a, b parse.Parameters) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for name, avals := range a {
+		bvals, ok := b[name]
+		if !ok || len(avals) != len(bvals) {
+			return false
+		}
+		for i := range avals {
+			if avals[i] != bvals[i] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
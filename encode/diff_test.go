@@ -0,0 +1,49 @@
+package encode_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bounoable/ical/encode"
+	"github.com/bounoable/ical/internal/testutil"
+	"github.com/bounoable/ical/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeEventDiff(t *testing.T) {
+	oldEvt := parse.Event{
+		Properties: []parse.Property{
+			testutil.Property("UID", "123", nil),
+			testutil.Property("DTSTART", "20200101T100000", parse.Parameters{"TZID": {"Europe/Berlin"}}),
+			testutil.Property("SUMMARY", "Meeting", nil),
+		},
+	}
+
+	newEvt := parse.Event{
+		Properties: []parse.Property{
+			testutil.Property("UID", "123", nil),
+			testutil.Property("DTSTART", "20200101T100000", parse.Parameters{"TZID": {"Europe/Berlin"}}),
+			testutil.Property("SUMMARY", "Weekly meeting", nil),
+		},
+	}
+
+	var buf strings.Builder
+	if err := encode.EncodeEventDiff(oldEvt, newEvt, &buf); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "SUMMARY:Weekly meeting\r\n", buf.String())
+
+	buf.Reset()
+	if err := encode.EncodeEventDiff(oldEvt, oldEvt, &buf); err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, buf.String())
+
+	newEvt.Properties[1].Params = parse.Parameters{"TZID": {"Europe/London"}}
+
+	buf.Reset()
+	if err := encode.EncodeEventDiff(oldEvt, newEvt, &buf); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "DTSTART;TZID=Europe/London:20200101T100000\r\nSUMMARY:Weekly meeting\r\n", buf.String())
+}
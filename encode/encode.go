@@ -5,46 +5,216 @@ import (
 	"io"
 	"sort"
 	"strings"
+	"time"
+	"unicode"
 	"unicode/utf8"
 
 	"github.com/bounoable/ical/parse"
 )
 
 // NewEncoder returns a new Encoder that writes to w.
-func NewEncoder(w io.Writer) *Encoder {
-	return &Encoder{w}
+func NewEncoder(w io.Writer, opts ...Option) *Encoder {
+	enc := Encoder{w: w}
+	for _, opt := range opts {
+		opt(&enc)
+	}
+	return &enc
+}
+
+// Encoder writes .ics files. The output is always valid UTF-8 without a byte order mark.
+type Encoder struct {
+	w           io.Writer
+	generateUID func(parse.Event) string
+	sortEvents  bool
+	preserveRaw bool
+	loc         *time.Location
+	productID   string
+	paramOrder  bool
 }
 
-// Encoder writes .ics files.
-type Encoder struct{ w io.Writer }
+// Option is an encoder option.
+type Option func(*Encoder)
+
+// SortEvents configures the encoder to write the events of a calendar in
+// chronological order of their Start. Events with the same Start keep their
+// order. By default, events are written in the order of the calendar.
+func SortEvents(enc *Encoder) {
+	enc.sortEvents = true
+}
+
+// PreserveRaw configures the encoder to write properties that have a Raw
+// content line (see lex.KeepRaw) byte by byte instead of formatting and
+// folding them, so that unchanged properties are encoded exactly as they
+// were parsed, e.g. for signed calendars.
+func PreserveRaw(enc *Encoder) {
+	enc.preserveRaw = true
+}
+
+// PreserveParamOrder configures the encoder to write the parameters of every
+// property in the order of its ParamOrder (see parse.KeepParamOrder) instead
+// of sorting them by name. Parameters that are not listed in ParamOrder are
+// written after the listed ones, sorted by name.
+func PreserveParamOrder(enc *Encoder) {
+	enc.paramOrder = true
+}
+
+// InTimezone configures the encoder to convert the DTSTART and DTEND of every
+// event to loc. The converted times are written with a TZID parameter of loc,
+// or in UTC if loc is time.UTC. DATE values (all-day events) are not converted.
+// The encoder doesn't write VTIMEZONE components for loc.
+func InTimezone(loc *time.Location) Option {
+	return func(enc *Encoder) {
+		enc.loc = loc
+	}
+}
+
+// LibraryProductID is the PRODID that DefaultProductID writes by default.
+const LibraryProductID = "-//bounoable//ical//EN"
+
+// DefaultProductID configures the encoder to write a PRODID property with the
+// value s for calendars that have no PRODID property, which is required by
+// RFC 5545. If s is empty, LibraryProductID is used. An existing PRODID is
+// never overridden, and a calendar without a PRODID property but with a
+// ProductID field gets a PRODID with the value of that field.
+func DefaultProductID(s string) Option {
+	return func(enc *Encoder) {
+		if s == "" {
+			s = LibraryProductID
+		}
+		enc.productID = s
+	}
+}
+
+// GenerateUID configures the encoder to write a UID property generated by fn
+// for every event that has neither a UID property nor a UID field. If fn is
+// nil, a UUID-like value is derived from a hash of the event and a counter,
+// so that identical events get distinct UIDs. Inject fn for deterministic UIDs.
+func GenerateUID(fn func(parse.Event) string) Option {
+	return func(enc *Encoder) {
+		if fn == nil {
+			fn = hashUID()
+		}
+		enc.generateUID = fn
+	}
+}
 
 // Encode writes cal as a .ics file to the writer.
 func (enc *Encoder) Encode(cal parse.Calendar) error {
-	var err error
-
-	if err = enc.string("BEGIN:VCALENDAR"); err != nil {
+	if err := enc.begin(cal.Properties, cal.ProductID); err != nil {
 		return err
 	}
 
-	for _, prop := range cal.Properties {
-		if err = enc.property(prop); err != nil {
-			return fmt.Errorf("encode property: %w", err)
+	for _, comp := range cal.Components {
+		if err := enc.component(comp); err != nil {
+			return fmt.Errorf("encode component: %w", err)
 		}
 	}
 
-	for _, evt := range cal.Events {
-		if err = enc.event(evt); err != nil {
+	events := cal.Events
+	if enc.sortEvents {
+		events = append([]parse.Event(nil), events...)
+		sort.SliceStable(events, func(a, b int) bool { return events[a].Start.Before(events[b].Start) })
+	}
+
+	for _, evt := range events {
+		if err := enc.event(evt); err != nil {
 			return fmt.Errorf("encode event: %w", err)
 		}
 	}
 
-	if err = enc.string("\r\nEND:VCALENDAR"); err != nil {
+	return enc.End()
+}
+
+// Begin writes the beginning of a calendar with the given calendar properties.
+// Use Begin, WriteEventFunc and End to stream a calendar without building a
+// parse.Calendar first:
+//
+//	enc.Begin(props...)
+//	for _, e := range events {
+//		enc.WriteEventFunc(func(pw *encode.PropertyWriter) {
+//			pw.Prop("UID", e.ID, nil)
+//		})
+//	}
+//	enc.End()
+func (enc *Encoder) Begin(props ...parse.Property) error {
+	return enc.begin(props, "")
+}
+
+// begin writes the beginning of a calendar. productID is the ProductID field
+// of the calendar, which is preferred over the DefaultProductID option if
+// props have no PRODID property.
+func (enc *Encoder) begin(props []parse.Property, productID string) error {
+	if err := enc.string("BEGIN:VCALENDAR"); err != nil {
 		return err
 	}
 
+	for _, prop := range props {
+		if err := enc.property(prop); err != nil {
+			return fmt.Errorf("encode property: %w", err)
+		}
+	}
+
+	if enc.productID != "" && !hasProperty(props, "PRODID") {
+		if productID == "" {
+			productID = enc.productID
+		}
+		if err := enc.property(parse.Property{Name: "PRODID", Value: productID}); err != nil {
+			return fmt.Errorf("encode property: %w", err)
+		}
+	}
+
 	return nil
 }
 
+func hasProperty(props []parse.Property, name string) bool {
+	for _, prop := range props {
+		if strings.EqualFold(prop.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// End writes the end of a calendar that has been started with Begin.
+func (enc *Encoder) End() error {
+	return enc.string("\r\nEND:VCALENDAR")
+}
+
+// WriteEventFunc writes an event whose properties are written by fn.
+// It returns the first error of the properties written by fn.
+func (enc *Encoder) WriteEventFunc(fn func(*PropertyWriter)) error {
+	if err := enc.string("\r\nBEGIN:VEVENT"); err != nil {
+		return err
+	}
+
+	pw := PropertyWriter{enc: enc}
+	fn(&pw)
+	if pw.err != nil {
+		return fmt.Errorf("encode event: %w", pw.err)
+	}
+
+	return enc.string("\r\nEND:VEVENT")
+}
+
+// PropertyWriter writes the properties of a component directly to the
+// underlying writer of an Encoder.
+type PropertyWriter struct {
+	enc *Encoder
+	err error
+}
+
+// Prop writes a property with the given name, value and params. After the
+// first error, all further calls of Prop are no-ops.
+func (pw *PropertyWriter) Prop(name, value string, params parse.Parameters) {
+	if pw.err != nil {
+		return
+	}
+
+	if err := pw.enc.property(parse.Property{Name: name, Params: params, Value: value}); err != nil {
+		pw.err = fmt.Errorf("encode property: %w", err)
+	}
+}
+
 func (enc *Encoder) write(p []byte) (int, error) {
 	n, err := enc.w.Write(p)
 	if err != nil {
@@ -61,6 +231,22 @@ func (enc *Encoder) string(s string) error {
 }
 
 func (enc *Encoder) property(prop parse.Property) error {
+	if enc.preserveRaw && prop.Raw != "" {
+		return enc.string("\r\n" + prop.Raw)
+	}
+
+	line, err := contentLine(prop, enc.paramOrder)
+	if err != nil {
+		return err
+	}
+
+	return enc.string("\r\n" + line)
+}
+
+// contentLine returns the folded content line of prop without a line break.
+// The parameters are sorted by name, unless paramOrder is true and prop has
+// a ParamOrder.
+func contentLine(prop parse.Property, paramOrder bool) (string, error) {
 	type parameter struct {
 		name   string
 		values []string
@@ -80,36 +266,44 @@ func (enc *Encoder) property(prop parse.Property) error {
 
 	sort.Slice(params, func(a, b int) bool { return params[a].name < params[b].name })
 
+	if paramOrder && len(prop.ParamOrder) > 0 {
+		rank := make(map[string]int, len(prop.ParamOrder))
+		for i, name := range prop.ParamOrder {
+			rank[name] = i + 1
+		}
+		// unlisted parameters have rank 0 and keep their sorted order after the listed ones
+		sort.SliceStable(params, func(a, b int) bool {
+			ra, rb := rank[params[a].name], rank[params[b].name]
+			return ra != 0 && (rb == 0 || ra < rb)
+		})
+	}
+
 	for _, param := range params {
 		if _, err = linebuilder.WriteString(";" + param.name); err != nil {
-			return fmt.Errorf("linebuilder: %w", err)
+			return "", fmt.Errorf("linebuilder: %w", err)
+		}
+		vals := make([]string, len(param.values))
+		for i, val := range param.values {
+			if err = validateParamValue(val); err != nil {
+				return "", fmt.Errorf("parameter %s: %w", param.name, err)
+			}
+			vals[i] = paramValue(val)
 		}
-		valstr := strings.Join(param.values, ",")
+		valstr := strings.Join(vals, ",")
 		if _, err = linebuilder.WriteString("=" + valstr); err != nil {
-			return fmt.Errorf("linebuilder: %w", err)
+			return "", fmt.Errorf("linebuilder: %w", err)
 		}
 	}
 
 	if _, err = linebuilder.WriteString(":" + prop.Value); err != nil {
-		return fmt.Errorf("linebuilder: %w", err)
+		return "", fmt.Errorf("linebuilder: %w", err)
 	}
 
-	line := linebuilder.String()
-
-	var splits []string
-
-	var l, r int
-	for l, r = 0, 75; r < len(line); l, r = r, r+75 {
-		for !utf8.RuneStart(line[r]) {
-			r--
-		}
-		splits = append(splits, line[l:r])
+	if !utf8.ValidString(linebuilder.String()) {
+		return "", fmt.Errorf("property %s: invalid UTF-8", prop.Name)
 	}
-	splits = append(splits, line[l:])
 
-	line = "\r\n" + strings.Join(splits, "\r\n ")
-
-	return enc.string(line)
+	return Fold(linebuilder.String(), 75), nil
 }
 
 func (enc *Encoder) event(evt parse.Event) error {
@@ -118,7 +312,15 @@ func (enc *Encoder) event(evt parse.Event) error {
 		return err
 	}
 
-	for _, prop := range evt.Properties {
+	if enc.loc != nil {
+		evt = inTimezone(evt, enc.loc)
+	}
+
+	if _, ok := evt.Property("UID"); !ok && evt.UID == "" && enc.generateUID != nil {
+		evt.UID = enc.generateUID(evt)
+	}
+
+	for _, prop := range eventProperties(evt) {
 		if err = enc.property(prop); err != nil {
 			return fmt.Errorf("encode property: %w", err)
 		}
@@ -130,6 +332,12 @@ func (enc *Encoder) event(evt parse.Event) error {
 		}
 	}
 
+	for _, comp := range evt.Components {
+		if err = enc.component(comp); err != nil {
+			return fmt.Errorf("encode component: %w", err)
+		}
+	}
+
 	return enc.string("\r\nEND:VEVENT")
 }
 
@@ -145,5 +353,86 @@ func (enc *Encoder) alarm(alarm parse.Alarm) error {
 		}
 	}
 
+	for _, comp := range alarm.Components {
+		if err = enc.component(comp); err != nil {
+			return fmt.Errorf("encode component: %w", err)
+		}
+	}
+
 	return enc.string("\r\nEND:VALARM")
 }
+
+func (enc *Encoder) component(comp parse.Component) error {
+	var err error
+	if err = enc.string("\r\nBEGIN:" + comp.Name); err != nil {
+		return err
+	}
+
+	for _, prop := range comp.Properties {
+		if err = enc.property(prop); err != nil {
+			return fmt.Errorf("encode property: %w", err)
+		}
+	}
+
+	for _, sub := range comp.Components {
+		if err = enc.component(sub); err != nil {
+			return fmt.Errorf("encode component: %w", err)
+		}
+	}
+
+	return enc.string("\r\nEND:" + comp.Name)
+}
+
+// validateParamValue returns an error if val contains a control character,
+// which cannot be represented in a parameter value, even if quoted.
+func validateParamValue(val string) error {
+	for _, r := range val {
+		if r != '\t' && unicode.IsControl(r) {
+			return fmt.Errorf("value %q contains control character %U", val, r)
+		}
+	}
+	return nil
+}
+
+// paramValue quotes val if it contains characters that are not allowed
+// in an unquoted parameter value. Already quoted values are returned as-is.
+func paramValue(val string) string {
+	if len(val) >= 2 && strings.HasPrefix(val, `"`) && strings.HasSuffix(val, `"`) {
+		return val
+	}
+
+	if strings.ContainsAny(val, ";:, \t") {
+		return `"` + val + `"`
+	}
+
+	return val
+}
+
+// Fold splits the content line s into lines of at most width octets, separated
+// by a CRLF followed by a single space. Multi-octet UTF-8 sequences are never split.
+// s is returned unchanged if width is not positive.
+func Fold(s string, width int) string {
+	if width <= 0 || len(s) <= width {
+		return s
+	}
+
+	var splits []string
+
+	var l, r int
+	for l, r = 0, width; r < len(s); l, r = r, r+width {
+		for r > l && !utf8.RuneStart(s[r]) {
+			r--
+		}
+
+		// width is smaller than the rune at l, so the rune gets its own line
+		if r == l {
+			_, size := utf8.DecodeRuneInString(s[l:])
+			r = l + size
+		}
+
+		splits = append(splits, s[l:r])
+	}
+	splits = append(splits, s[l:])
+
+	return strings.Join(splits, "\r\n ")
+}
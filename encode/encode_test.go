@@ -2,11 +2,16 @@ package encode_test
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/bounoable/ical/encode"
 	"github.com/bounoable/ical/internal/testutil"
+	"github.com/bounoable/ical/lex"
 	"github.com/bounoable/ical/parse"
 	"github.com/stretchr/testify/assert"
 )
@@ -92,3 +97,610 @@ END:VCALENDAR`,
 		})
 	}
 }
+
+func TestEncoder_Encode_quotedParams(t *testing.T) {
+	cal := parse.Calendar{
+		Events: []parse.Event{{
+			Properties: []parse.Property{
+				testutil.Property("ATTACH", "https://x.io/f", parse.Parameters{
+					"X-FILENAME": []string{"my file.txt"},
+					"X-LABELS":   []string{"a:b", `"c;d"`, "e"},
+				}),
+			},
+		}},
+	}
+
+	var buf strings.Builder
+	if err := encode.NewEncoder(&buf).Encode(cal); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Contains(t, buf.String(), "\r\nATTACH;X-FILENAME=\"my file.txt\";X-LABELS=\"a:b\",\"c;d\",e:https://x.io/f\r\n")
+
+	parsed, err := parse.Items(lex.Text(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prop, ok := parsed.Events[0].Property("ATTACH")
+	assert.True(t, ok)
+
+	filename, ok := prop.Params.First("X-FILENAME")
+	assert.True(t, ok)
+	assert.Equal(t, "my file.txt", filename)
+	assert.Equal(t, []string{`"a:b"`, `"c;d"`, "e"}, prop.Params["X-LABELS"])
+	assert.Equal(t, "https://x.io/f", prop.Value)
+}
+
+func TestEncoder_Encode_withoutEvents(t *testing.T) {
+	cal := parse.Calendar{
+		Properties: []parse.Property{
+			testutil.Property("VERSION", "2.0", nil),
+			testutil.Property("PRODID", "-//Example//Product//ID//EN", nil),
+			testutil.Property("METHOD", "PUBLISH", nil),
+		},
+	}
+
+	var buf strings.Builder
+	if err := encode.NewEncoder(&buf).Encode(cal); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Example//Product//ID//EN\r\nMETHOD:PUBLISH\r\nEND:VCALENDAR", buf.String())
+
+	parsed, err := parse.Items(lex.Text(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, parsed.IsEmpty())
+	assert.Equal(t, cal.Properties, parsed.Properties)
+	assert.Equal(t, "2.0", parsed.Version)
+	assert.Equal(t, "PUBLISH", parsed.Method)
+}
+
+func TestEncoder_Encode_categories(t *testing.T) {
+	cal, err := parse.Items(lex.Text("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:1\r\nCATEGORIES:MEETING\\, WEEKLY,WORK\r\nEND:VEVENT\r\nEND:VCALENDAR"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"MEETING, WEEKLY", "WORK"}, cal.Events[0].Categories)
+
+	// drop the raw property, so that it is synthesized from Categories
+	cal.Events[0].Properties = cal.Events[0].Properties[:1]
+
+	var buf strings.Builder
+	if err := encode.NewEncoder(&buf).Encode(cal); err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, buf.String(), "\r\nCATEGORIES:MEETING\\, WEEKLY,WORK\r\n")
+
+	parsed, err := parse.Items(lex.Text(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"MEETING, WEEKLY", "WORK"}, parsed.Events[0].Categories)
+}
+
+func TestGenerateUID(t *testing.T) {
+	cal := parse.Calendar{
+		Events: []parse.Event{
+			{Summary: "Meeting"},
+			{Summary: "Meeting"},
+			{UID: "existing"},
+		},
+	}
+
+	var buf strings.Builder
+	if err := encode.NewEncoder(&buf, encode.GenerateUID(nil)).Encode(cal); err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := parse.Items(lex.Text(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, parsed.Events, 3)
+	assert.NotEmpty(t, parsed.Events[0].UID)
+	assert.NotEmpty(t, parsed.Events[1].UID)
+	assert.NotEqual(t, parsed.Events[0].UID, parsed.Events[1].UID)
+	assert.Equal(t, "existing", parsed.Events[2].UID)
+}
+
+func TestGenerateUID_custom(t *testing.T) {
+	cal := parse.Calendar{Events: []parse.Event{{Summary: "A"}, {Summary: "B"}}}
+
+	var buf strings.Builder
+	enc := encode.NewEncoder(&buf, encode.GenerateUID(func(evt parse.Event) string {
+		return evt.Summary + "@example.com"
+	}))
+	if err := enc.Encode(cal); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Contains(t, buf.String(), "\r\nUID:A@example.com\r\n")
+	assert.Contains(t, buf.String(), "\r\nUID:B@example.com\r\n")
+}
+
+func TestEncoder_Encode_proximityAlarm(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:1\r\n" +
+		"BEGIN:VALARM\r\n" +
+		"ACTION:DISPLAY\r\n" +
+		"TRIGGER;VALUE=DATE-TIME:19760401T005545Z\r\n" +
+		"PROXIMITY:DEPART\r\n" +
+		"BEGIN:VLOCATION\r\n" +
+		"UID:123456-abcdef-98765432\r\n" +
+		"URL:geo:40.443,-79.945;u=10\r\n" +
+		"END:VLOCATION\r\n" +
+		"END:VALARM\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR"
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf strings.Builder
+	if err := encode.NewEncoder(&buf).Encode(cal); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, input, buf.String())
+
+	parsed, err := parse.Items(lex.Text(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, cal.Events[0].Alarms, parsed.Events[0].Alarms)
+	assert.Equal(t, "DEPART", parsed.Events[0].Alarms[0].Proximity)
+}
+
+func TestSortEvents(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2020, time.January, d, 10, 0, 0, 0, time.UTC) }
+	cal := parse.Calendar{
+		Events: []parse.Event{
+			{UID: "3", Start: day(3)},
+			{UID: "1", Start: day(1)},
+			{UID: "2a", Start: day(2)},
+			{UID: "2b", Start: day(2)},
+		},
+	}
+
+	var buf strings.Builder
+	if err := encode.NewEncoder(&buf, encode.SortEvents).Encode(cal); err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := parse.Items(lex.Text(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var uids []string
+	for _, evt := range parsed.Events {
+		uids = append(uids, evt.UID)
+	}
+	assert.Equal(t, []string{"1", "2a", "2b", "3"}, uids)
+	assert.Equal(t, "3", cal.Events[0].UID, "the calendar must not be modified")
+}
+
+func TestInTimezone(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:1\r\n" +
+		"DTSTART:20200101T150000Z\r\n" +
+		"DTEND:20200101T160000Z\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:2\r\n" +
+		"DTSTART;VALUE=DATE:20200102\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR"
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal.Events = append(cal.Events, parse.Event{UID: "3", Start: time.Date(2020, time.January, 3, 12, 0, 0, 0, time.UTC)})
+
+	var buf strings.Builder
+	if err := encode.NewEncoder(&buf, encode.InTimezone(testutil.LoadLocation("America/New_York"))).Encode(cal); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "BEGIN:VCALENDAR\r\n"+
+		"BEGIN:VEVENT\r\n"+
+		"UID:1\r\n"+
+		"DTSTART;TZID=America/New_York:20200101T100000\r\n"+
+		"DTEND;TZID=America/New_York:20200101T110000\r\n"+
+		"END:VEVENT\r\n"+
+		"BEGIN:VEVENT\r\n"+
+		"UID:2\r\n"+
+		"DTSTART;VALUE=DATE:20200102\r\n"+
+		"END:VEVENT\r\n"+
+		"BEGIN:VEVENT\r\n"+
+		"UID:3\r\n"+
+		"DTSTART;TZID=America/New_York:20200103T070000\r\n"+
+		"END:VEVENT\r\n"+
+		"END:VCALENDAR", buf.String())
+	assert.Equal(t, "20200101T150000Z", cal.Events[0].Properties[1].Value, "the calendar must not be modified")
+}
+
+func TestPreserveRaw(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:1\r\n" +
+		"DTSTAMP;X-SIGNED=\"yes\":20200101T1\r\n 00000Z\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR"
+
+	cal, err := parse.Items(lex.Text(input, lex.KeepRaw))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evt := &cal.Events[0]
+	evt.Properties = append(evt.Properties, parse.Property{
+		Name:   "SUMMARY",
+		Params: parse.Parameters{"LANGUAGE": {"en"}},
+		Value:  "Added",
+	})
+
+	var buf strings.Builder
+	if err := encode.NewEncoder(&buf, encode.PreserveRaw).Encode(cal); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "BEGIN:VCALENDAR\r\n"+
+		"BEGIN:VEVENT\r\n"+
+		"UID:1\r\n"+
+		"DTSTAMP;X-SIGNED=\"yes\":20200101T1\r\n 00000Z\r\n"+
+		"SUMMARY;LANGUAGE=en:Added\r\n"+
+		"END:VEVENT\r\n"+
+		"END:VCALENDAR", buf.String())
+
+	buf.Reset()
+	if err := encode.NewEncoder(&buf).Encode(cal); err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, buf.String(), "\r\nDTSTAMP;X-SIGNED=\"yes\":20200101T100000Z\r\n")
+}
+
+func TestEncoder_Encode_escapeTypedFields(t *testing.T) {
+	cal := parse.Calendar{
+		Events: []parse.Event{{
+			UID:     "1",
+			Summary: "Lunch, then meeting",
+			URL:     `https://example.com/files\share`,
+		}},
+	}
+
+	var buf strings.Builder
+	if err := encode.NewEncoder(&buf).Encode(cal); err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, buf.String(), "\r\nSUMMARY:Lunch\\, then meeting\r\n")
+	assert.Contains(t, buf.String(), "\r\nURL:https://example.com/files\\share\r\n")
+
+	parsed, err := parse.Items(lex.Text(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Lunch, then meeting", parsed.Events[0].Summary)
+	assert.Equal(t, `https://example.com/files\share`, parsed.Events[0].URL)
+}
+
+func TestEncoder_Encode_invalidParamValue(t *testing.T) {
+	cal := parse.Calendar{
+		Events: []parse.Event{{
+			Properties: []parse.Property{
+				testutil.Property("ATTENDEE", "mailto:jane@example.com", parse.Parameters{
+					"CN": []string{"Jane\nDoe"},
+				}),
+			},
+		}},
+	}
+
+	var buf strings.Builder
+	err := encode.NewEncoder(&buf).Encode(cal)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "parameter CN")
+	assert.Contains(t, err.Error(), "control character U+000A")
+}
+
+func TestEncoder_Encode_invalidUTF8(t *testing.T) {
+	cal := parse.Calendar{
+		Events: []parse.Event{{
+			Properties: []parse.Property{
+				testutil.Property("SUMMARY", "Caf\xe9", nil),
+			},
+		}},
+	}
+
+	var buf strings.Builder
+	err := encode.NewEncoder(&buf).Encode(cal)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "property SUMMARY: invalid UTF-8")
+}
+
+func TestFold(t *testing.T) {
+	line := "DESCRIPTION:" + strings.Repeat("Lorem ipsum dolor sit amet, ä€😀 ", 10)
+	folded := encode.Fold(line, 75)
+
+	for _, l := range strings.Split(folded, "\r\n") {
+		assert.LessOrEqual(t, len(strings.TrimPrefix(l, " ")), 75)
+		assert.True(t, utf8.ValidString(l), "line %q is not valid UTF-8", l)
+	}
+	assert.Equal(t, line, lex.Unfold(folded))
+}
+
+func TestFold_narrowWidth(t *testing.T) {
+	assert.Equal(t, "a\r\n 😀\r\n b", encode.Fold("a😀b", 1))
+	assert.Equal(t, "short", encode.Fold("short", 75))
+	assert.Equal(t, "unlimited", encode.Fold("unlimited", 0))
+}
+
+func TestEncoder_WriteEventFunc(t *testing.T) {
+	var buf strings.Builder
+	enc := encode.NewEncoder(&buf)
+
+	if err := enc.Begin(testutil.Property("VERSION", "2.0", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, uid := range []string{"1", "2"} {
+		if err := enc.WriteEventFunc(func(pw *encode.PropertyWriter) {
+			pw.Prop("UID", uid, nil)
+			pw.Prop("DTSTART", "20200101", parse.Parameters{"VALUE": {"DATE"}})
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := enc.End(); err != nil {
+		t.Fatal(err)
+	}
+
+	var expected strings.Builder
+	if err := encode.NewEncoder(&expected).Encode(parse.Calendar{
+		Properties: []parse.Property{testutil.Property("VERSION", "2.0", nil)},
+		Events: []parse.Event{
+			{Properties: []parse.Property{
+				testutil.Property("UID", "1", nil),
+				testutil.Property("DTSTART", "20200101", parse.Parameters{"VALUE": {"DATE"}}),
+			}},
+			{Properties: []parse.Property{
+				testutil.Property("UID", "2", nil),
+				testutil.Property("DTSTART", "20200101", parse.Parameters{"VALUE": {"DATE"}}),
+			}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, expected.String(), buf.String())
+}
+
+func TestEncoder_WriteEventFunc_error(t *testing.T) {
+	var buf strings.Builder
+	err := encode.NewEncoder(&buf).WriteEventFunc(func(pw *encode.PropertyWriter) {
+		pw.Prop("SUMMARY", "Caf\xe9", nil)
+		pw.Prop("UID", "1", nil)
+	})
+
+	assert.Error(t, err)
+	assert.NotContains(t, buf.String(), "UID")
+}
+
+const benchmarkEvents = 10000
+
+func BenchmarkEncoder_Encode(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		cal := parse.Calendar{Events: make([]parse.Event, benchmarkEvents)}
+		for j := range cal.Events {
+			cal.Events[j].Properties = []parse.Property{
+				{Name: "UID", Value: "111111111111"},
+				{Name: "DTSTART", Value: "20200101T103000Z"},
+				{Name: "SUMMARY", Value: "Lorem ipsum dolor sit amet"},
+			}
+		}
+
+		if err := encode.NewEncoder(io.Discard).Encode(cal); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncoder_WriteEventFunc(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		enc := encode.NewEncoder(io.Discard)
+		if err := enc.Begin(); err != nil {
+			b.Fatal(err)
+		}
+
+		for j := 0; j < benchmarkEvents; j++ {
+			if err := enc.WriteEventFunc(func(pw *encode.PropertyWriter) {
+				pw.Prop("UID", "111111111111", nil)
+				pw.Prop("DTSTART", "20200101T103000Z", nil)
+				pw.Prop("SUMMARY", "Lorem ipsum dolor sit amet", nil)
+			}); err != nil {
+				b.Fatal(err)
+			}
+		}
+
+		if err := enc.End(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDefaultProductID(t *testing.T) {
+	cal := parse.Calendar{
+		Properties: []parse.Property{testutil.Property("VERSION", "2.0", nil)},
+	}
+
+	var buf strings.Builder
+	if err := encode.NewEncoder(&buf, encode.DefaultProductID("")).Encode(cal); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//bounoable//ical//EN\r\nEND:VCALENDAR", buf.String())
+
+	buf.Reset()
+	if err := encode.NewEncoder(&buf, encode.DefaultProductID("-//Example//App//EN")).Encode(cal); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Example//App//EN\r\nEND:VCALENDAR", buf.String())
+
+	cal.ProductID = "-//Example//Field//EN"
+
+	buf.Reset()
+	if err := encode.NewEncoder(&buf, encode.DefaultProductID("")).Encode(cal); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Example//Field//EN\r\nEND:VCALENDAR", buf.String())
+
+	cal.Properties = append(cal.Properties, testutil.Property("PRODID", "-//Example//Product//ID//EN", nil))
+
+	buf.Reset()
+	if err := encode.NewEncoder(&buf, encode.DefaultProductID("")).Encode(cal); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Example//Product//ID//EN\r\nEND:VCALENDAR", buf.String())
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	for _, file := range []string{"../testdata/messy.ics", "../parse/testdata/properties_after_events.ics", "../lex/testdata/calendar_crlf.ics"} {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(b))
+	}
+	f.Add("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY;X-A=\"a:b\",c:Foo\\, bar\r\nATTACH;X-FILENAME=\"x;y\":https://x.io\r\nBEGIN:VALARM\r\nTRIGGER:-PT15M\r\nEND:VALARM\r\nEND:VEVENT\r\nEND:VCALENDAR")
+
+	f.Fuzz(func(t *testing.T, input string) {
+		cal, err := parse.Items(lex.Text(input))
+		if err != nil {
+			return
+		}
+
+		var first strings.Builder
+		if err := encode.NewEncoder(&first).Encode(cal); err != nil {
+			return
+		}
+
+		reparsed, err := parse.Items(lex.Text(first.String()))
+		if err != nil {
+			t.Fatalf("parse encoded calendar: %v\n%q", err, first.String())
+		}
+
+		var second strings.Builder
+		if err := encode.NewEncoder(&second).Encode(reparsed); err != nil {
+			t.Fatalf("encode reparsed calendar: %v", err)
+		}
+
+		assert.Equal(t, first.String(), second.String())
+		assert.Equal(t, unquotedParams(cal.Properties), unquotedParams(reparsed.Properties))
+		if assert.Equal(t, len(cal.Events), len(reparsed.Events)) {
+			for i := range cal.Events {
+				assert.Equal(t, unquotedParams(cal.Events[i].Properties), unquotedParams(reparsed.Events[i].Properties))
+			}
+		}
+	})
+}
+
+// unquotedParams returns a copy of props with unquoted parameter values,
+// because the encoder may quote values that have been parsed without quotes.
+func unquotedParams(props []parse.Property) []parse.Property {
+	res := make([]parse.Property, len(props))
+	for i, prop := range props {
+		res[i] = prop
+		res[i].Params = make(parse.Parameters, len(prop.Params))
+		for name := range prop.Params {
+			res[i].Params[name] = prop.Params.Values(name)
+		}
+	}
+	return res
+}
+
+func TestEncoder_Encode_attendeeOrder(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:1\r\n" +
+		"ATTENDEE;RSVP=TRUE;CN=Eve:mailto:eve@example.com\r\n" +
+		"ATTENDEE;CN=Bob:mailto:bob@example.com\r\n" +
+		"ATTENDEE;ROLE=CHAIR;CN=Dave:mailto:dave@example.com\r\n" +
+		"ATTENDEE:mailto:alice@example.com\r\n" +
+		"ATTENDEE;PARTSTAT=ACCEPTED;CN=Carol:mailto:carol@example.com\r\n" +
+		"END:VEVENT\r\nEND:VCALENDAR"
+
+	expected := []string{"eve@example.com", "bob@example.com", "dave@example.com", "alice@example.com", "carol@example.com"}
+
+	emails := func(evt parse.Event) []string {
+		var emails []string
+		for _, att := range evt.Attendees {
+			emails = append(emails, att.Email)
+		}
+		return emails
+	}
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, expected, emails(cal.Events[0]))
+
+	var buf strings.Builder
+	if err := encode.NewEncoder(&buf).Encode(cal); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Contains(t, buf.String(), "ATTENDEE;CN=Eve;RSVP=TRUE:mailto:eve@example.com\r\n"+
+		"ATTENDEE;CN=Bob:mailto:bob@example.com\r\n"+
+		"ATTENDEE;CN=Dave;ROLE=CHAIR:mailto:dave@example.com\r\n"+
+		"ATTENDEE:mailto:alice@example.com\r\n"+
+		"ATTENDEE;CN=Carol;PARTSTAT=ACCEPTED:mailto:carol@example.com\r\n")
+
+	reparsed, err := parse.Items(lex.Text(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, expected, emails(reparsed.Events[0]))
+}
+
+func TestPreserveParamOrder(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\n" +
+		"ATTENDEE;RSVP=TRUE;CN=Eve;ROLE=CHAIR:mailto:eve@example.com\r\n" +
+		"END:VEVENT\r\nEND:VCALENDAR"
+
+	cal, err := parse.Items(lex.Text(input), parse.KeepParamOrder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"RSVP", "CN", "ROLE"}, cal.Events[0].Properties[0].ParamOrder)
+
+	var buf strings.Builder
+	if err := encode.NewEncoder(&buf).Encode(cal); err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, buf.String(), "\r\nATTENDEE;CN=Eve;ROLE=CHAIR;RSVP=TRUE:mailto:eve@example.com\r\n")
+
+	buf.Reset()
+	if err := encode.NewEncoder(&buf, encode.PreserveParamOrder).Encode(cal); err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, buf.String(), "\r\nATTENDEE;RSVP=TRUE;CN=Eve;ROLE=CHAIR:mailto:eve@example.com\r\n")
+
+	// parameters that have been added after parsing are written last
+	cal.Events[0].Properties[0].Params["PARTSTAT"] = []string{"ACCEPTED"}
+	cal.Events[0].Properties[0].Params["X-A"] = []string{"1"}
+
+	buf.Reset()
+	if err := encode.NewEncoder(&buf, encode.PreserveParamOrder).Encode(cal); err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, buf.String(), "\r\nATTENDEE;RSVP=TRUE;CN=Eve;ROLE=CHAIR;PARTSTAT=ACCEPTED;X-A=1:mailto:eve@exa\r\n mple.com\r\n")
+}
@@ -0,0 +1,73 @@
+package encode
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bounoable/ical/parse"
+)
+
+// FormatDuration formats d as a DURATION value (https://tools.ietf.org/html/rfc5545#section-3.3.6),
+// e.g. "PT1H30M", "P1D" or "-P2W". Fractions of seconds are truncated.
+func FormatDuration(d time.Duration) string {
+	var b strings.Builder
+	if d < 0 {
+		b.WriteByte('-')
+		d = -d
+	}
+	b.WriteByte('P')
+
+	const day = 24 * time.Hour
+	const week = 7 * day
+
+	if d >= week && d%week == 0 {
+		b.WriteString(strconv.FormatInt(int64(d/week), 10) + "W")
+		return b.String()
+	}
+
+	days := d / day
+	if days > 0 {
+		b.WriteString(strconv.FormatInt(int64(days), 10) + "D")
+	}
+
+	d = (d - days*day).Truncate(time.Second)
+	if d == 0 {
+		if days == 0 {
+			b.WriteString("T0S")
+		}
+		return b.String()
+	}
+
+	b.WriteByte('T')
+	for _, unit := range []struct {
+		d      time.Duration
+		suffix string
+	}{{time.Hour, "H"}, {time.Minute, "M"}, {time.Second, "S"}} {
+		if n := d / unit.d; n > 0 {
+			b.WriteString(strconv.FormatInt(int64(n), 10) + unit.suffix)
+			d -= n * unit.d
+		}
+	}
+
+	return b.String()
+}
+
+// FormatPeriod formats p as a PERIOD value (https://tools.ietf.org/html/rfc5545#section-3.3.9).
+// A period with a Duration is formatted as "start/duration", any other period as
+// "start/end". UTC times are formatted with the "Z" suffix and times in other
+// locations as local times, so that the TZID parameter of their property must
+// be set by the caller.
+func FormatPeriod(p parse.Period) string {
+	if p.Duration != 0 {
+		return formatDateTime(p.Start) + "/" + FormatDuration(p.Duration)
+	}
+	return formatDateTime(p.Start) + "/" + formatDateTime(p.End)
+}
+
+func formatDateTime(t time.Time) string {
+	if t.Location() == time.UTC {
+		return t.Format(layoutDateTimeUTC)
+	}
+	return t.Format(layoutDateTimeLocal)
+}
@@ -0,0 +1,76 @@
+package encode_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bounoable/ical/encode"
+	"github.com/bounoable/ical/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		90 * time.Minute:                   "PT1H30M",
+		0:                                  "PT0S",
+		15 * time.Second:                   "PT15S",
+		24 * time.Hour:                     "P1D",
+		25*time.Hour + 30*time.Second:      "P1DT1H30S",
+		14 * 24 * time.Hour:                "P2W",
+		8 * 24 * time.Hour:                 "P8D",
+		-15 * time.Minute:                  "-PT15M",
+		time.Minute + 500*time.Millisecond: "PT1M",
+	}
+
+	for d, expected := range tests {
+		t.Run(expected, func(t *testing.T) {
+			formatted := encode.FormatDuration(d)
+			assert.Equal(t, expected, formatted)
+
+			parsed, err := parse.Duration(formatted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, d.Truncate(time.Second), parsed)
+		})
+	}
+}
+
+func TestFormatPeriod(t *testing.T) {
+	start := time.Date(1997, time.March, 8, 16, 0, 0, 0, time.UTC)
+
+	tests := map[string]parse.Period{
+		"19970308T160000Z/19970308T203000Z": {Start: start, End: start.Add(4*time.Hour + 30*time.Minute)},
+		"19970308T160000Z/PT1H30M":          {Start: start, Duration: 90 * time.Minute},
+	}
+
+	for expected, period := range tests {
+		t.Run(expected, func(t *testing.T) {
+			formatted := encode.FormatPeriod(period)
+			assert.Equal(t, expected, formatted)
+
+			parts := strings.SplitN(formatted, "/", 2)
+			parsedStart, err := parse.Time(parse.Property{Name: "DTSTART", Value: parts[0]})
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, period.Start, parsedStart)
+
+			if period.Duration != 0 {
+				d, err := parse.Duration(parts[1])
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, period.Duration, d)
+				return
+			}
+
+			parsedEnd, err := parse.Time(parse.Property{Name: "DTEND", Value: parts[1]})
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, period.End, parsedEnd)
+		})
+	}
+}
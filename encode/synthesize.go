@@ -0,0 +1,133 @@
+package encode
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bounoable/ical/parse"
+)
+
+const (
+	layoutDateTimeUTC   = "20060102T150405Z"
+	layoutDateTimeLocal = "20060102T150405"
+)
+
+// eventProperties returns the properties of evt, followed by the properties
+// that are synthesized from the typed fields of evt. A property is only
+// synthesized if evt has no property with the same name, so the typed fields
+// of parsed events don't override their raw properties.
+func eventProperties(evt parse.Event) []parse.Property {
+	var synth []parse.Property
+
+	add := func(name string, prop parse.Property, ok bool) {
+		if !ok {
+			return
+		}
+		if _, exists := evt.Property(name); exists {
+			return
+		}
+		prop.Name = name
+		synth = append(synth, prop)
+	}
+
+	add("UID", valueProperty("UID", evt.UID), evt.UID != "")
+	add("DTSTAMP", parse.Property{Value: evt.Timestamp.UTC().Format(layoutDateTimeUTC)}, !evt.Timestamp.IsZero())
+	// The End of parsed events may be implied by DTSTART or DURATION, so DTEND
+	// is only synthesized together with DTSTART.
+	if _, ok := evt.Property("DTSTART"); !ok && !evt.Start.IsZero() {
+		add("DTSTART", timeProperty(evt.Start), true)
+		add("DTEND", timeProperty(evt.End), !evt.End.IsZero())
+	}
+	add("SUMMARY", valueProperty("SUMMARY", evt.Summary), evt.Summary != "")
+	add("DESCRIPTION", valueProperty("DESCRIPTION", evt.Description), evt.Description != "")
+	add("LOCATION", valueProperty("LOCATION", evt.Location), evt.Location != "")
+	add("URL", valueProperty("URL", evt.URL), evt.URL != "")
+
+	if len(evt.Categories) > 0 {
+		cats := make([]string, len(evt.Categories))
+		for i, cat := range evt.Categories {
+			cats[i] = escapeText(cat)
+		}
+		add("CATEGORIES", parse.Property{Value: strings.Join(cats, ",")}, true)
+	}
+
+	if len(synth) == 0 {
+		return evt.Properties
+	}
+
+	props := make([]parse.Property, 0, len(evt.Properties)+len(synth))
+	return append(append(props, evt.Properties...), synth...)
+}
+
+// inTimezone returns a copy of evt whose Start and End, and their DTSTART and
+// DTEND properties, are converted to loc. DATE values are kept as they are.
+func inTimezone(evt parse.Event, loc *time.Location) parse.Event {
+	dtstart, ok := evt.Property("DTSTART")
+	if !ok {
+		// DTSTART and DTEND are synthesized from Start and End
+		evt.Start, evt.End = evt.Start.In(loc), evt.End.In(loc)
+		return evt
+	}
+
+	evt.Properties = append([]parse.Property(nil), evt.Properties...)
+	if dtstart.ValueType() != "DATE" {
+		evt.SetStart(evt.Start.In(loc), false)
+	}
+	if dtend, ok := evt.Property("DTEND"); ok && dtend.ValueType() != "DATE" {
+		evt.SetEnd(evt.End.In(loc), false)
+	}
+
+	return evt
+}
+
+// valueProperty returns a property with the given value, which is escaped if
+// the default value type of the property is TEXT.
+func valueProperty(name, val string) parse.Property {
+	prop := parse.Property{Name: name, Value: val}
+	if prop.ValueType() == "TEXT" {
+		prop.Value = escapeText(val)
+	}
+	return prop
+}
+
+// timeProperty returns a DATE-TIME property for t. UTC times are written in
+// UTC, times in time.Local are written as floating times and times in other
+// locations are written with a TZID parameter.
+func timeProperty(t time.Time) parse.Property {
+	switch t.Location() {
+	case time.UTC:
+		return parse.Property{Value: t.Format(layoutDateTimeUTC)}
+	case time.Local:
+		return parse.Property{Value: t.Format(layoutDateTimeLocal)}
+	default:
+		return parse.Property{
+			Params: parse.Parameters{"TZID": {t.Location().String()}},
+			Value:  t.Format(layoutDateTimeLocal),
+		}
+	}
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+// escapeText escapes a TEXT value (https://tools.ietf.org/html/rfc5545#section-3.3.11).
+func escapeText(val string) string {
+	return textEscaper.Replace(val)
+}
+
+// hashUID returns a UID generator that derives UUID-like values from a SHA-1
+// hash of the properties of an event and a counter of the generated UIDs.
+func hashUID() func(parse.Event) string {
+	var n int
+	return func(evt parse.Event) string {
+		n++
+		h := sha1.New()
+		fmt.Fprintf(h, "%d\n", n)
+		for _, prop := range eventProperties(evt) {
+			fmt.Fprintf(h, "%s:%s\n", prop.Name, prop.Value)
+		}
+		sum := h.Sum(nil)
+		return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+	}
+}
@@ -0,0 +1,94 @@
+package ical_test
+
+import (
+	"encoding"
+	"testing"
+	"time"
+
+	"github.com/bounoable/ical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalendar_ContentType(t *testing.T) {
+	assert.Equal(t, "text/calendar; charset=utf-8", ical.Calendar{}.ContentType())
+	assert.Equal(t, "text/calendar; charset=utf-8; method=PUBLISH", ical.Calendar{Method: "PUBLISH"}.ContentType())
+}
+
+func TestMarshal_setStart(t *testing.T) {
+	cal, err := ical.ParseText("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:1\r\nDTSTART:20200101T100000Z\r\nEND:VEVENT\r\nEND:VCALENDAR")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cal.Events[0].SetStart(time.Date(2020, time.February, 1, 8, 0, 0, 0, time.UTC), false)
+
+	b, err := ical.Marshal(cal)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:1\r\nDTSTART:20200201T080000Z\r\nEND:VEVENT\r\nEND:VCALENDAR", string(b))
+}
+
+func TestCalendar_MarshalText(t *testing.T) {
+	text := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:1\r\nDTSTART:20200101T100000Z\r\nSUMMARY:Meeting\r\nEND:VEVENT\r\nEND:VCALENDAR"
+
+	cal, err := ical.ParseText(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var _ encoding.TextMarshaler = cal
+	var _ encoding.TextUnmarshaler = &cal
+
+	b, err := cal.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, text, string(b))
+
+	var unmarshaled ical.Calendar
+	if err := unmarshaled.UnmarshalText(b); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, cal, unmarshaled)
+}
+
+func TestMarshal_components(t *testing.T) {
+	text := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"BEGIN:VTIMEZONE\r\n" +
+		"TZID:Europe/Berlin\r\n" +
+		"BEGIN:STANDARD\r\n" +
+		"DTSTART:19701025T030000\r\n" +
+		"TZOFFSETFROM:+0200\r\n" +
+		"TZOFFSETTO:+0100\r\n" +
+		"END:STANDARD\r\n" +
+		"END:VTIMEZONE\r\n" +
+		"BEGIN:VAVAILABILITY\r\n" +
+		"UID:availability\r\n" +
+		"BEGIN:AVAILABLE\r\n" +
+		"DTSTART:20200101T090000Z\r\n" +
+		"END:AVAILABLE\r\n" +
+		"END:VAVAILABILITY\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:1\r\n" +
+		"DTSTART;TZID=Europe/Berlin:20200101T100000\r\n" +
+		"BEGIN:X-CUSTOM\r\n" +
+		"X-FOO:bar\r\n" +
+		"END:X-CUSTOM\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR"
+
+	cal, err := ical.ParseText(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ical.Marshal(cal)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, text, string(b))
+}
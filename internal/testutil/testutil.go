@@ -45,6 +45,16 @@ func EndAlarm() lex.Item {
 	return Item(lex.AlarmEnd, "END:VALARM")
 }
 
+// BeginComponent creates a lex.ComponentBegin item for the component with the given name.
+func BeginComponent(name string) lex.Item {
+	return Item(lex.ComponentBegin, "BEGIN:"+name)
+}
+
+// EndComponent creates a lex.ComponentEnd item for the component with the given name.
+func EndComponent(name string) lex.Item {
+	return Item(lex.ComponentEnd, "END:"+name)
+}
+
 // Property creates a parse.Property.
 func Property(name, val string, params parse.Parameters) parse.Property {
 	if params == nil {
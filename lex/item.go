@@ -13,17 +13,32 @@ const (
 	EventEnd
 	AlarmBegin
 	AlarmEnd
+	// ComponentBegin and ComponentEnd are the begin / end of components that
+	// don't have dedicated item types, e.g. VTIMEZONE.
+	ComponentBegin
+	ComponentEnd
 
 	Name
 	Value
 	ParamName
 	ParamValue
+
+	// Warning is a non-fatal problem of the input, e.g. a line that exceeds
+	// the limit of MaxOctetsPerLine.
+	Warning
 )
 
 // Item is a lexed item.
 type Item struct {
 	Type  ItemType
 	Value string
+	// Raw is the original content line of a Value item, including its line
+	// folding but without the line break at the end. Raw is only set by the
+	// KeepRaw option.
+	Raw string
+	// Line is the 1-based line of the input that a Warning item refers to.
+	// Line is 0 for all other items.
+	Line int
 }
 
 // ItemType is the type of a lexed item.
@@ -45,6 +60,10 @@ func (it ItemType) String() string {
 		return "<alarm:begin>"
 	case AlarmEnd:
 		return "<alarm:end>"
+	case ComponentBegin:
+		return "<component:begin>"
+	case ComponentEnd:
+		return "<component:end>"
 	case Name:
 		return "<contentline:name>"
 	case ParamName:
@@ -53,13 +72,15 @@ func (it ItemType) String() string {
 		return "<param:value>"
 	case Value:
 		return "<contentline:value>"
+	case Warning:
+		return "<warning>"
 	default:
 		return "<unknown>"
 	}
 }
 
 func (i Item) String() string {
-	if i.Type == Error {
+	if i.Type == Error || i.Type == Warning {
 		return i.Value
 	}
 
@@ -9,7 +9,6 @@ import (
 	"io"
 	"os"
 	"strings"
-	"unicode"
 	"unicode/utf8"
 )
 
@@ -62,13 +61,51 @@ func File(filepath string, opts ...Option) (<-chan Item, error) {
 
 // Text lexes the iCalendar from the given text.
 func Text(text string, opts ...Option) <-chan Item {
-	return Reader(strings.NewReader(text))
+	return Reader(strings.NewReader(text), opts...)
+}
+
+// Lines lexes the iCalendar from the given lines, which are joined with CRLF
+// line breaks. Lines that start with a space or a horizontal tab continue the
+// previous line, so lines may be folded or unfolded.
+func Lines(lines []string, opts ...Option) <-chan Item {
+	return Text(strings.Join(lines, "\r\n"), opts...)
+}
+
+// Unfold removes the line folding from s (https://tools.ietf.org/html/rfc5545#section-3.1).
+// A line break ("CRLF" or "LF") that is followed by a space or a horizontal tab
+// is removed together with that whitespace character.
+func Unfold(s string) string {
+	if !strings.Contains(s, "\n") {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\r' && i+2 < len(s) && s[i+1] == '\n' && isFoldSpace(rune(s[i+2])):
+			i += 2
+		case s[i] == '\n' && i+1 < len(s) && isFoldSpace(rune(s[i+1])):
+			i++
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String()
+}
+
+// isFoldSpace determines if r is a whitespace character that continues a folded line.
+func isFoldSpace(r rune) bool {
+	return r == ' ' || r == '\t'
 }
 
 // Option is a lexer option.
 type Option func(*lexer)
 
-// Context adds a context to the lexer.
+// Context adds a context to the lexer. When ctx is canceled, the lexer stops
+// and sends an Error item as the last item of the channel.
 func Context(ctx context.Context) Option {
 	return func(l *lexer) {
 		l.ctx = ctx
@@ -81,24 +118,93 @@ func StrictLineBreaks(l *lexer) {
 	l.strictLineBreaks = true
 }
 
+// MaxOctetsPerLine configures the lexer to emit a Warning item for every line
+// of the input that exceeds n octets, excluding the line break. RFC 5545
+// recommends to fold lines longer than 75 octets
+// (https://tools.ietf.org/html/rfc5545#section-3.1), so overly long lines
+// indicate a producer that doesn't fold its output.
+func MaxOctetsPerLine(n int) Option {
+	return func(l *lexer) {
+		l.maxOctets = n
+	}
+}
+
+// KeepRaw configures the lexer to set the Raw field of Value items to the
+// original content line, so that unchanged lines can be written byte by byte.
+func KeepRaw(l *lexer) {
+	l.keepRaw = true
+}
+
+// LenientParams configures the lexer to drop parameters that have a name but
+// no "=" and value, like "VALUE" in "DTSTART;VALUE:20200101", instead of
+// emitting an Error item. The input after the ":" is lexed as the value.
+func LenientParams(l *lexer) {
+	l.lenientParams = true
+}
+
 type lexer struct {
 	ctx              context.Context
 	strictLineBreaks bool
+	lenientParams    bool
+	keepRaw          bool
+	maxOctets        int
 	input            io.RuneReader
-	bufferedInput    string
+	bufferedInput    []byte
 	bufPos           int
 	width            int
 	consumed         int
 	items            chan Item
+
+	// raw is the original input since rawBase (KeepRaw only)
+	raw     []byte
+	rawBase int
+	// folds are the positions in the unfolded input at which line folds have been removed
+	folds []fold
+	// foldedBytes is the number of bytes of the discarded folds
+	foldedBytes int
+	lineStart   int
+
+	// line and lineOctets are the number and the length of the current line of the input (MaxOctetsPerLine only)
+	line       int
+	lineOctets int
+}
+
+// fold is a line fold of n bytes that has been removed before pos of the unfolded input.
+type fold struct {
+	pos int
+	n   int
 }
 
 type stateFunc func(*lexer) stateFunc
 
+// send sends item to the item channel unless the context of the lexer is
+// canceled, so that the lexer stops when its consumer stops receiving items
+// and cancels the context.
+func (l *lexer) send(item Item) {
+	select {
+	case l.items <- item:
+	case <-l.ctx.Done():
+	}
+}
+
 func (l *lexer) emit(t ItemType) {
-	l.items <- Item{
+	l.send(Item{
 		Type:  t,
-		Value: l.bufferedInput[:l.bufPos],
+		Value: string(l.bufferedInput[:l.bufPos]),
+	})
+	l.ignore()
+}
+
+// emitValue emits the Value item of a content line.
+func (l *lexer) emitValue() {
+	item := Item{
+		Type:  Value,
+		Value: string(l.bufferedInput[:l.bufPos]),
 	}
+	if l.keepRaw {
+		item.Raw = l.rawLine()
+	}
+	l.send(item)
 	l.ignore()
 }
 
@@ -132,10 +238,10 @@ func (l *lexer) next() (r rune) {
 			break
 		}
 
-		l.items <- Item{
+		l.send(Item{
 			Type:  Error,
 			Value: err.Error(),
-		}
+		})
 		break
 	}
 
@@ -144,58 +250,164 @@ func (l *lexer) next() (r rune) {
 		return eof
 	}
 
-	r, l.width = utf8.DecodeRuneInString(l.bufferedInput[l.bufPos:])
+	if b := l.bufferedInput[l.bufPos]; b < utf8.RuneSelf {
+		r, l.width = rune(b), 1
+	} else {
+		r, l.width = utf8.DecodeRune(l.bufferedInput[l.bufPos:])
+	}
 	l.bufPos += l.width
 
 	return
 }
 
 func (l *lexer) readRune() error {
-	r, _, err := l.input.ReadRune()
+	r, err := l.read()
 	if err != nil {
 		return err
 	}
 
 	// if first rune is not one of [CR, LF], add it to the input and return
 	if r != cr && r != lf {
-		l.bufferedInput += string(r)
+		l.appendRune(r)
 		return nil
 	}
 
-	r2, _, err := l.input.ReadRune()
+	r2, err := l.read()
+	if errors.Is(err, io.EOF) {
+		// the input ends with a line break, which must still be lexed as
+		// the end of the last line; EOF is returned by the next read
+		l.appendRune(r)
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
-	// if the first rune is LF and the second is a space, unfold by skipping these two runes
-	if r == lf && unicode.IsSpace(r2) {
+	// if the first rune is LF and the second is a space or tab, unfold by skipping these two runes
+	if r == lf && isFoldSpace(r2) {
+		l.addFold(2)
 		return nil
 	}
 
 	// if r + r2 != CRLF, add both runes to the input
 	if !(r == cr && r2 == lf) {
-		l.bufferedInput += string(r) + string(r2)
+		l.appendRune(r)
+		l.appendRune(r2)
 		return nil
 	}
 
-	r3, _, err := l.input.ReadRune()
+	r3, err := l.read()
+	if errors.Is(err, io.EOF) {
+		l.appendRune(r)
+		l.appendRune(r2)
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
 	// r = CR, r2 = LF
-	// if r3 is not a space, add a CRLF line break and r3 to the input
-	if !unicode.IsSpace(r3) {
-		l.bufferedInput += string(r) + string(r2) + string(r3)
+	// if r3 is not a space or tab, add a CRLF line break and r3 to the input
+	if !isFoldSpace(r3) {
+		l.appendRune(r)
+		l.appendRune(r2)
+		l.appendRune(r3)
 		return nil
 	}
 
-	// r + r2 = CRLF, r3 = SPACE -> drop all three runes
+	// r + r2 = CRLF, r3 = SPACE or TAB -> drop all three runes
+	l.addFold(3)
 	return nil
 }
 
+// read reads the next rune of the input and records it if the lexer keeps the raw input.
+func (l *lexer) read() (rune, error) {
+	r, _, err := l.input.ReadRune()
+	if err == nil && l.keepRaw {
+		l.raw = utf8.AppendRune(l.raw, r)
+	}
+	if l.maxOctets > 0 {
+		l.countOctets(r, err)
+	}
+	return r, err
+}
+
+// countOctets counts the octets of the current line and emits a Warning
+// item at the end of a line that exceeds the limit of MaxOctetsPerLine.
+func (l *lexer) countOctets(r rune, err error) {
+	switch {
+	case err == nil && r == cr:
+	case err == nil && r != lf:
+		l.lineOctets += utf8.RuneLen(r)
+	default:
+		l.line++
+		if l.lineOctets > l.maxOctets {
+			l.send(Item{
+				Type:  Warning,
+				Value: fmt.Sprintf("line %d has %d octets; the maximum is %d", l.line, l.lineOctets, l.maxOctets),
+				Line:  l.line,
+			})
+		}
+		l.lineOctets = 0
+	}
+}
+
+func (l *lexer) addFold(n int) {
+	if l.keepRaw {
+		l.folds = append(l.folds, fold{pos: l.consumed + len(l.bufferedInput), n: n})
+	}
+}
+
+// rawOffset returns the position in the raw input of pos in the unfolded input.
+func (l *lexer) rawOffset(pos int) int {
+	off := pos + l.foldedBytes
+	for _, f := range l.folds {
+		if f.pos <= pos {
+			off += f.n
+		}
+	}
+	return off
+}
+
+// rawLine returns the raw content line that has been lexed since the start of
+// the current line and discards the raw input up to the end of that line.
+func (l *lexer) rawLine() string {
+	end := l.pos()
+	from, to := l.rawOffset(l.lineStart)-l.rawBase, l.rawOffset(end)-l.rawBase
+	if from < 0 || to > len(l.raw) || from > to {
+		return ""
+	}
+	line := string(l.raw[from:to])
+
+	l.raw = l.raw[to:]
+	l.rawBase += to
+	folds := l.folds[:0]
+	for _, f := range l.folds {
+		if f.pos > end {
+			folds = append(folds, f)
+			continue
+		}
+		l.foldedBytes += f.n
+	}
+	l.folds = folds
+
+	return line
+}
+
+// appendRune appends r to the buffered input. ASCII runes, which make up
+// most calendars, are appended without UTF-8 encoding.
+func (l *lexer) appendRune(r rune) {
+	if r < utf8.RuneSelf {
+		l.bufferedInput = append(l.bufferedInput, byte(r))
+		return
+	}
+	l.bufferedInput = utf8.AppendRune(l.bufferedInput, r)
+}
+
 func (l *lexer) ignore() {
-	l.bufferedInput = l.bufferedInput[l.bufPos:]
+	// move the unconsumed input to the start of the buffer to reuse its capacity
+	n := copy(l.bufferedInput, l.bufferedInput[l.bufPos:])
+	l.bufferedInput = l.bufferedInput[:n]
 	l.consumed += l.bufPos
 	l.bufPos = 0
 }
@@ -221,21 +433,22 @@ func (l *lexer) hasPrefix(prefix string) bool {
 			break
 		}
 
-		l.items <- Item{
+		l.send(Item{
 			Type:  Error,
 			Value: err.Error(),
-		}
+		})
 		return false
 	}
 
-	return strings.HasPrefix(l.bufferedInput[l.bufPos:], prefix)
+	rest := l.bufferedInput[l.bufPos:]
+	return len(rest) >= len(prefix) && string(rest[:len(prefix)]) == prefix
 }
 
 func (l *lexer) errorf(format string, args ...interface{}) stateFunc {
-	l.items <- Item{
+	l.send(Item{
 		Type:  Error,
 		Value: fmt.Sprintf(format, args...),
-	}
+	})
 	return nil
 }
 
@@ -1,9 +1,11 @@
 package lex_test
 
 import (
+	"bytes"
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/bounoable/ical/internal/testutil"
@@ -189,6 +191,80 @@ func TestReader(t *testing.T) {
 				testutil.Item(lex.EOF, ""),
 			},
 		},
+		"components": {
+			filepath: filepath.Join(wd, "testdata/components.ics"),
+			expected: []lex.Item{
+				testutil.BeginCalendar(),
+				testutil.BeginComponent("VTIMEZONE"),
+				testutil.Item(lex.Name, "TZID"),
+				testutil.Item(lex.Value, "Custom"),
+				testutil.BeginComponent("STANDARD"),
+				testutil.Item(lex.Name, "DTSTART"),
+				testutil.Item(lex.Value, "19701025T030000"),
+				testutil.Item(lex.Name, "TZOFFSETFROM"),
+				testutil.Item(lex.Value, "+0200"),
+				testutil.Item(lex.Name, "TZOFFSETTO"),
+				testutil.Item(lex.Value, "+0100"),
+				testutil.EndComponent("STANDARD"),
+				testutil.EndComponent("VTIMEZONE"),
+				testutil.BeginEvent(),
+				testutil.BeginComponent("X-CUSTOM"),
+				testutil.Item(lex.Name, "X-FOO"),
+				testutil.Item(lex.Value, "bar"),
+				testutil.EndComponent("X-CUSTOM"),
+				testutil.EndEvent(),
+				testutil.EndCalendar(),
+				testutil.Item(lex.EOF, ""),
+			},
+		},
+		"folded param values": {
+			filepath: filepath.Join(wd, "testdata/folded_param_values.ics"),
+			expected: []lex.Item{
+				testutil.BeginCalendar(),
+				testutil.BeginEvent(),
+				testutil.Item(lex.Name, "ATTENDEE"),
+				testutil.Item(lex.ParamName, "CN"),
+				testutil.Item(lex.ParamValue, `"John Doe"`),
+				testutil.Item(lex.ParamName, "ROLE"),
+				testutil.Item(lex.ParamValue, "REQ-PARTICIPANT"),
+				testutil.Item(lex.ParamName, "DELEGATED-FROM"),
+				testutil.Item(lex.ParamValue, `"mailto:jsmith@example.com"`),
+				testutil.Item(lex.ParamName, "PARTSTAT"),
+				testutil.Item(lex.ParamValue, "ACCEPTED"),
+				testutil.Item(lex.Value, "mailto:jdoe@example.com"),
+				testutil.EndEvent(),
+				testutil.EndCalendar(),
+				testutil.Item(lex.EOF, ""),
+			},
+		},
+		"quoted param values": {
+			filepath: filepath.Join(wd, "testdata/quoted_param_values.ics"),
+			expected: []lex.Item{
+				testutil.BeginCalendar(),
+				testutil.BeginEvent(),
+				testutil.Item(lex.Name, "ATTACH"),
+				testutil.Item(lex.ParamName, "X-FILENAME"),
+				testutil.Item(lex.ParamValue, `"my file.txt"`),
+				testutil.Item(lex.ParamName, "X-LABELS"),
+				testutil.Item(lex.ParamValue, `"a:b"`),
+				testutil.Item(lex.ParamValue, `"c;d"`),
+				testutil.Item(lex.ParamValue, "e"),
+				testutil.Item(lex.Value, "https://example.com/file.txt"),
+				testutil.EndEvent(),
+				testutil.EndCalendar(),
+				testutil.Item(lex.EOF, ""),
+			},
+		},
+		"name without value": {
+			filepath: filepath.Join(wd, "testdata/name_only.ics"),
+			expected: []lex.Item{
+				testutil.BeginCalendar(),
+				testutil.BeginEvent(),
+				testutil.Item(lex.Name, "UID"),
+				testutil.Item(lex.Value, "123"),
+				testutil.Item(lex.Error, `missing ':' after name "COMMENT" at pos 47`),
+			},
+		},
 		"with alarm": {
 			filepath: filepath.Join(wd, "testdata/with_alarm.ics"),
 			expected: []lex.Item{
@@ -223,6 +299,21 @@ func TestReader(t *testing.T) {
 				testutil.Item(lex.EOF, ""),
 			},
 		},
+		"trailing lone CR": {
+			filepath: filepath.Join(wd, "testdata/trailing_cr.ics"),
+			opts:     []lex.Option{lex.StrictLineBreaks},
+			expected: []lex.Item{
+				testutil.BeginCalendar(),
+				testutil.Item(lex.Name, "VERSION"),
+				testutil.Item(lex.Value, "2.0"),
+				testutil.BeginEvent(),
+				testutil.Item(lex.Name, "UID"),
+				testutil.Item(lex.Value, "1"),
+				testutil.EndEvent(),
+				testutil.EndCalendar(),
+				testutil.Item(lex.EOF, ""),
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -268,3 +359,232 @@ func TestLex_context(t *testing.T) {
 		Value: ctx.Err().Error(),
 	}, items[len(items)-1])
 }
+
+func TestText_options(t *testing.T) {
+	var items []lex.Item
+	for item := range lex.Text("BEGIN:VCALENDAR\nEND:VCALENDAR", lex.StrictLineBreaks) {
+		items = append(items, item)
+	}
+
+	assert.Equal(t, lex.Error, items[len(items)-1].Type)
+}
+
+func TestLines(t *testing.T) {
+	expected := []lex.Item{
+		testutil.BeginCalendar(),
+		testutil.BeginEvent(),
+		testutil.Item(lex.Name, "SUMMARY"),
+		testutil.Item(lex.Value, "This is a long summary"),
+		testutil.EndEvent(),
+		testutil.EndCalendar(),
+		testutil.Item(lex.EOF, ""),
+	}
+
+	tests := map[string][]string{
+		"unfolded": {
+			"BEGIN:VCALENDAR",
+			"BEGIN:VEVENT",
+			"SUMMARY:This is a long summary",
+			"END:VEVENT",
+			"END:VCALENDAR",
+		},
+		"folded": {
+			"BEGIN:VCALENDAR",
+			"BEGIN:VEVENT",
+			"SUMMARY:This is a ",
+			" long",
+			"\t summary",
+			"END:VEVENT",
+			"END:VCALENDAR",
+		},
+	}
+
+	for name, lines := range tests {
+		t.Run(name, func(t *testing.T) {
+			var items []lex.Item
+			for item := range lex.Lines(lines, lex.StrictLineBreaks) {
+				items = append(items, item)
+			}
+
+			assert.Equal(t, expected, items)
+		})
+	}
+}
+
+func TestKeepRaw(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\n" +
+		"DTSTAMP;X-FOO=\"bar\":20200101T1\r\n 00000Z\r\n" +
+		"SUMMARY:Foo\n\tbar\r\n" +
+		"END:VCALENDAR"
+
+	var values []lex.Item
+	for item := range lex.Text(input, lex.KeepRaw) {
+		if item.Type == lex.Value {
+			values = append(values, item)
+		}
+	}
+
+	assert.Equal(t, []lex.Item{
+		{Type: lex.Value, Value: "20200101T100000Z", Raw: "DTSTAMP;X-FOO=\"bar\":20200101T1\r\n 00000Z"},
+		{Type: lex.Value, Value: "Foobar", Raw: "SUMMARY:Foo\n\tbar"},
+	}, values)
+}
+
+func TestMaxOctetsPerLine(t *testing.T) {
+	long := "DESCRIPTION:" + strings.Repeat("a", 188)
+	folded := "SUMMARY:" + strings.Repeat("a", 67) + "\r\n " + strings.Repeat("a", 74)
+	input := "BEGIN:VCALENDAR\r\n" + long + "\r\n" + folded + "\r\nEND:VCALENDAR"
+
+	var warnings []lex.Item
+	for item := range lex.Text(input, lex.MaxOctetsPerLine(75)) {
+		if item.Type == lex.Warning {
+			warnings = append(warnings, item)
+		}
+	}
+
+	assert.Equal(t, []lex.Item{
+		{Type: lex.Warning, Value: "line 2 has 200 octets; the maximum is 75", Line: 2},
+	}, warnings)
+}
+
+func TestUnfold(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "unfolded",
+			input:    "SUMMARY:Foo\r\nDESCRIPTION:Bar",
+			expected: "SUMMARY:Foo\r\nDESCRIPTION:Bar",
+		},
+		{
+			name:     "CRLF + space",
+			input:    "DESCRIPTION:This is a lo\r\n ng description",
+			expected: "DESCRIPTION:This is a long description",
+		},
+		{
+			name:     "LF + space",
+			input:    "DESCRIPTION:This is a lo\n ng description",
+			expected: "DESCRIPTION:This is a long description",
+		},
+		{
+			name:     "CRLF + tab",
+			input:    "DESCRIPTION:This is a lo\r\n\tng description",
+			expected: "DESCRIPTION:This is a long description",
+		},
+		{
+			name:     "only the first whitespace is removed",
+			input:    "DESCRIPTION:This is a\r\n  long description",
+			expected: "DESCRIPTION:This is a long description",
+		},
+		{
+			name:     "multiple folds",
+			input:    "SUMMARY:F\r\n o\n o\r\nDESCRIPTION:B\r\n ar",
+			expected: "SUMMARY:Foo\r\nDESCRIPTION:Bar",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, lex.Unfold(test.input))
+		})
+	}
+}
+
+func benchmarkASCIIInput(events int) string {
+	var b strings.Builder
+	b.WriteString("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Example//Benchmark//EN\r\n")
+	for i := 0; i < events; i++ {
+		b.WriteString("BEGIN:VEVENT\r\nUID:1234567890@example.com\r\n")
+		b.WriteString("DTSTART;TZID=Europe/Berlin:20200101T100000\r\nDTEND;TZID=Europe/Berlin:20200101T110000\r\n")
+		b.WriteString("SUMMARY:Lorem ipsum dolor sit amet\r\n")
+		b.WriteString("DESCRIPTION:" + strings.Repeat("Lorem ipsum dolor sit amet. ", 20) + "\r\n")
+		b.WriteString("END:VEVENT\r\n")
+	}
+	b.WriteString("END:VCALENDAR")
+	return b.String()
+}
+
+func BenchmarkText_ascii(b *testing.B) {
+	input := benchmarkASCIIInput(100)
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for item := range lex.Text(input) {
+			if item.Type == lex.Error {
+				b.Fatal(item.Value)
+			}
+		}
+	}
+}
+
+func TestLenientParams(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\nDTSTART;TZID=UTC;VALUE:20200101\r\nEND:VCALENDAR"
+
+	var items []lex.Item
+	for item := range lex.Text(input) {
+		items = append(items, item)
+	}
+
+	assert.Equal(t, []lex.Item{
+		testutil.BeginCalendar(),
+		testutil.Item(lex.Name, "DTSTART"),
+		testutil.Item(lex.ParamName, "TZID"),
+		testutil.Item(lex.ParamValue, "UTC"),
+		testutil.Item(lex.Error, `parameter "VALUE" at pos 34 has no value (missing '=')`),
+	}, items)
+
+	items = nil
+	for item := range lex.Text(input, lex.LenientParams) {
+		items = append(items, item)
+	}
+
+	assert.Equal(t, []lex.Item{
+		testutil.BeginCalendar(),
+		testutil.Item(lex.Name, "DTSTART"),
+		testutil.Item(lex.ParamName, "TZID"),
+		testutil.Item(lex.ParamValue, "UTC"),
+		testutil.Item(lex.Value, "20200101"),
+		testutil.EndCalendar(),
+		testutil.Item(lex.EOF, ""),
+	}, items)
+}
+
+func FuzzLex(f *testing.F) {
+	files, _ := filepath.Glob(filepath.Join(wd, "testdata", "*.ics"))
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+	f.Add([]byte("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nBEGIN:VALARM\r\nEND:VEVENT\r\nEND:VCALENDAR"))
+	f.Add([]byte("BEGIN:VCALENDAR\r\nSUMMARY;X-A=\"\xe2\x82:\xff\r\n \xe2"))
+	f.Add([]byte("BEGIN:VCALENDAR\r\nDTSTART;VALUE:20200101\r\nX-\r\n\r\n\r"))
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		var items []lex.Item
+		for item := range lex.Reader(bytes.NewReader(input), lex.KeepRaw, lex.MaxOctetsPerLine(75)) {
+			items = append(items, item)
+		}
+
+		if len(items) == 0 {
+			t.Fatal("lexer closed the channel without an EOF or Error item")
+		}
+
+		last := items[len(items)-1]
+		if last.Type != lex.EOF && last.Type != lex.Error {
+			t.Fatalf("last item is %v; expected EOF or Error", last)
+		}
+
+		for _, item := range items[:len(items)-1] {
+			if item.Type == lex.EOF || item.Type == lex.Error {
+				t.Fatalf("lexer emitted %v before the last item", item)
+			}
+		}
+	})
+}
@@ -14,10 +14,14 @@ const (
 	endVEvent      = "END:VEVENT"
 	beginVAlarm    = "BEGIN:VALARM"
 	endVAlarm      = "END:VALARM"
+	begin          = "BEGIN:"
+	end            = "END:"
 )
 
 // contentline   = name *(";" param ) ":" value CRLF
 func lexContentLine(l *lexer) stateFunc {
+	l.lineStart = l.pos()
+
 	if l.hasPrefix(beginVCalender) {
 		l.advance(len(beginVCalender))
 		l.emit(CalendarBegin)
@@ -54,9 +58,31 @@ func lexContentLine(l *lexer) stateFunc {
 		return lexNewLine
 	}
 
+	if l.hasPrefix(begin) {
+		l.advance(len(begin))
+		return lexComponent(ComponentBegin)
+	}
+
+	if l.hasPrefix(end) {
+		l.advance(len(end))
+		return lexComponent(ComponentEnd)
+	}
+
 	return lexName
 }
 
+// lexComponent lexes the component name of a BEGIN / END line
+// and emits the whole line as an item of type t.
+func lexComponent(t ItemType) stateFunc {
+	return func(l *lexer) stateFunc {
+		for isNameChar(l.next()) {
+		}
+		l.backup()
+		l.emit(t)
+		return lexNewLine
+	}
+}
+
 func lexNewLine(l *lexer) stateFunc {
 	r := l.next()
 	if r == eof {
@@ -66,6 +92,11 @@ func lexNewLine(l *lexer) stateFunc {
 
 	if r == cr {
 		r = l.next()
+		// some exporters end the input with a lone CR
+		if r == eof {
+			l.emitEOF()
+			return nil
+		}
 	} else if r == lf && l.strictLineBreaks {
 		return l.errorf("missing carriage return (CR) at pos %d", l.pos())
 	}
@@ -91,14 +122,15 @@ func lexNewLine(l *lexer) stateFunc {
 func lexName(l *lexer) stateFunc {
 	for {
 		r := l.next()
-		if r == eof {
-			return l.unexpectedEOF()
-		}
-
 		if isNameChar(r) {
 			continue
 		}
 
+		if r == eof || r == cr || r == lf {
+			l.backup()
+			return l.errorf("missing ':' after name %q at pos %d", l.bufferedInput[:l.bufPos], l.pos())
+		}
+
 		l.backup()
 		l.emitAdvanced(Name)
 
@@ -123,14 +155,15 @@ func lexName(l *lexer) stateFunc {
 // CONTROL       = %x00-08 / %x0A-1F / %x7F ; All the controls except HTAB
 func lexValue(l *lexer) stateFunc {
 	if l.hasPrefix("\r\n") || l.hasPrefix("\n") {
-		l.emit(Value)
+		l.emitValue()
 		return lexNewLine
 	}
 
 	for {
 		r := l.next()
 		if r == eof {
-			l.emit(Value)
+			l.emitValue()
+			l.emitEOF()
 			return nil
 		}
 
@@ -139,12 +172,29 @@ func lexValue(l *lexer) stateFunc {
 		}
 
 		l.backup()
-		l.emitAdvanced(Value)
+		if l.bufPos > 0 {
+			l.emitValue()
+		}
 
 		return lexNewLine
 	}
 }
 
+// lexParamWithoutValue handles a parameter name that is directly followed by
+// the ":" of the property value, like in "DTSTART;VALUE:20200101". By default
+// this is an error. With LenientParams the parameter is dropped and the input
+// after the ":" is lexed as the property value.
+func lexParamWithoutValue(l *lexer) stateFunc {
+	if !l.lenientParams {
+		name := string(l.bufferedInput[:l.bufPos])
+		return l.errorf("parameter %q at pos %d has no value (missing '=')", name, l.pos()-l.bufPos)
+	}
+
+	l.next()
+	l.ignore()
+	return lexValue
+}
+
 // param         = param-name "=" param-value *("," param-value)
 // param-name    = iana-token / x-name
 // iana-token    = 1*(ALPHA / DIGIT / "-")
@@ -162,6 +212,11 @@ func lexParamName(l *lexer) stateFunc {
 		}
 
 		l.backup()
+
+		if l.peek() == ':' && l.bufPos > 0 {
+			return lexParamWithoutValue
+		}
+
 		l.emitAdvanced(ParamName)
 
 		r = l.next()
@@ -187,6 +242,10 @@ func lexParamName(l *lexer) stateFunc {
 // NON-US-ASCII  = UTF8-2 / UTF8-3 / UTF8-4 ; UTF8-2, UTF8-3, and UTF8-4 are defined in [RFC3629]
 // CONTROL       = %x00-08 / %x0A-1F / %x7F ; All the controls except HTAB
 func lexParamValue(l *lexer) stateFunc {
+	if l.peek() == '"' {
+		return lexQuotedParamValue
+	}
+
 	for {
 		r := l.next()
 		if r == eof {
@@ -201,27 +260,60 @@ func lexParamValue(l *lexer) stateFunc {
 		l.backup()
 		l.emitAdvanced(ParamValue)
 
-		r = l.next()
+		return lexParamValueEnd
+	}
+}
 
-		switch r {
-		case ':':
-			l.ignore()
-			return lexValue
-		case ';':
-			l.ignore()
-			return lexParamName
-		case ',':
-			l.ignore()
-			return lexParamValue
+// quoted-string = DQUOTE *QSAFE-CHAR DQUOTE
+//
+// The surrounding double quotes are kept in the emitted value.
+func lexQuotedParamValue(l *lexer) stateFunc {
+	l.next() // opening DQUOTE
+
+	for {
+		r := l.next()
+		if r == eof {
+			return l.unexpectedEOF()
+		}
+
+		if r == '"' {
+			break
 		}
 
-		return l.unexpected(r, ':')
+		if !isQSafeChar(r) {
+			return l.unexpected(r, '"')
+		}
 	}
+
+	l.emit(ParamValue)
+
+	return lexParamValueEnd
+}
+
+func lexParamValueEnd(l *lexer) stateFunc {
+	r := l.next()
+
+	switch r {
+	case ':':
+		l.ignore()
+		return lexValue
+	case ';':
+		l.ignore()
+		return lexParamName
+	case ',':
+		l.ignore()
+		return lexParamValue
+	}
+
+	return l.unexpected(r, ':', ';', ',')
 }
 
 // isNameChar checks if r is a unicode letter / digit or '-'
 func isNameChar(r rune) bool {
-	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-'
+	if r < utf8.RuneSelf {
+		return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '-'
+	}
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
 }
 
 // isQSafeChar check if r is a unicode letter / digit / '-' or '"'
@@ -236,5 +328,9 @@ func isSafeChar(r rune) bool {
 
 // isValueChar checks if r is a utf-8 control character or '\t'
 func isValueChar(r rune) bool {
-	return r == '\t' || (!unicode.IsControl(r) && utf8.ValidRune(r))
+	if r < utf8.RuneSelf {
+		// ASCII fast path: everything but the controls %x00-08 / %x0A-1F / %x7F
+		return r == '\t' || (r >= 0x20 && r != 0x7f)
+	}
+	return !unicode.IsControl(r) && utf8.ValidRune(r)
 }
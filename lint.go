@@ -0,0 +1,210 @@
+package ical
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/bounoable/ical/lex"
+	"github.com/bounoable/ical/parse"
+)
+
+// Severity is the severity of a LintIssue.
+type Severity string
+
+// The severities of lint issues.
+const (
+	// SeverityError is the severity of problems that make the iCalendar invalid.
+	SeverityError = Severity("error")
+	// SeverityWarning is the severity of problems that consumers may not handle.
+	SeverityWarning = Severity("warning")
+)
+
+// LintIssue is a problem of an iCalendar that has been found by Lint.
+type LintIssue struct {
+	// Line is the 1-based line of the source in which the problem occurs,
+	// or 0 if the problem cannot be attributed to a line.
+	Line     int
+	Severity Severity
+	Message  string
+}
+
+func (issue LintIssue) String() string {
+	if issue.Line == 0 {
+		return fmt.Sprintf("%s: %s", issue.Severity, issue.Message)
+	}
+	return fmt.Sprintf("line %d: %s: %s", issue.Line, issue.Severity, issue.Message)
+}
+
+// knownComponents are the components of RFC 5545 and its extensions.
+var knownComponents = map[string]bool{
+	"VCALENDAR":     true,
+	"VEVENT":        true,
+	"VTODO":         true,
+	"VJOURNAL":      true,
+	"VFREEBUSY":     true,
+	"VTIMEZONE":     true,
+	"STANDARD":      true,
+	"DAYLIGHT":      true,
+	"VALARM":        true,
+	"VAVAILABILITY": true,
+	"AVAILABLE":     true,
+	"VLOCATION":     true,
+	"VRESOURCE":     true,
+	"PARTICIPANT":   true,
+}
+
+// lintTimeProperties are the properties whose values are linted as DATE / DATE-TIME values.
+var lintTimeProperties = map[string]bool{
+	"DTSTART":       true,
+	"DTEND":         true,
+	"DTSTAMP":       true,
+	"DUE":           true,
+	"RECURRENCE-ID": true,
+	"CREATED":       true,
+	"LAST-MODIFIED": true,
+}
+
+// Lint reports the problems of the iCalendar in r together with the lines
+// in which they occur. Unlike Parse, Lint doesn't stop at the first problem:
+//   - events without a UID or DTSTAMP
+//   - DTSTAMP values that are not in UTC (without a "Z" suffix)
+//   - invalid DATE / DATE-TIME values
+//   - lines that exceed 75 octets and should have been folded (see lex.MaxOctetsPerLine)
+//   - components that are neither defined by RFC 5545 (or its extensions) nor "X-" components
+//
+// A lexer error is reported in the line in which it occurs. If the iCalendar
+// cannot be parsed for another reason, the parse error is reported as an issue
+// without a line. Issues are sorted by line.
+func Lint(r io.Reader) []LintIssue {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return []LintIssue{{Severity: SeverityError, Message: err.Error()}}
+	}
+
+	var items []lex.Item
+	for item := range lex.Reader(bytes.NewReader(b), lex.KeepRaw, lex.MaxOctetsPerLine(75)) {
+		items = append(items, item)
+	}
+
+	l := linter{}
+	l.lint(items)
+
+	if !l.hasErrors() {
+		if _, err := parse.FromSlice(items); err != nil {
+			l.issues = append(l.issues, LintIssue{Severity: SeverityError, Message: err.Error()})
+		}
+	}
+
+	sort.SliceStable(l.issues, func(a, b int) bool { return l.issues[a].Line < l.issues[b].Line })
+
+	return l.issues
+}
+
+type linter struct {
+	issues []LintIssue
+}
+
+// lintComponent is a component that is being linted.
+type lintComponent struct {
+	name  string
+	line  int
+	props map[string]bool
+}
+
+func (l *linter) addf(line int, sev Severity, format string, args ...interface{}) {
+	l.issues = append(l.issues, LintIssue{
+		Line:     line,
+		Severity: sev,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (l *linter) hasErrors() bool {
+	for _, issue := range l.issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// lint lints the lexed items. The items must be lexed with lex.KeepRaw, so
+// that the lines of folded properties can be counted.
+func (l *linter) lint(items []lex.Item) {
+	var stack []lintComponent
+	var prop parse.Property
+	var param string
+	line := 1
+
+	for _, item := range items {
+		switch item.Type {
+		case lex.Error:
+			l.addf(line, SeverityError, "%s", item.Value)
+			return
+		case lex.Warning:
+			l.addf(item.Line, SeverityWarning, "%s", item.Value)
+		case lex.CalendarBegin, lex.EventBegin, lex.AlarmBegin, lex.ComponentBegin:
+			name := strings.TrimPrefix(item.Value, "BEGIN:")
+			if upper := strings.ToUpper(name); !knownComponents[upper] && !strings.HasPrefix(upper, "X-") {
+				l.addf(line, SeverityWarning, "unknown component %s", name)
+			}
+			stack = append(stack, lintComponent{name: strings.ToUpper(name), line: line, props: make(map[string]bool)})
+			line++
+		case lex.CalendarEnd, lex.EventEnd, lex.AlarmEnd, lex.ComponentEnd:
+			if len(stack) > 0 {
+				l.lintComponent(stack[len(stack)-1])
+				stack = stack[:len(stack)-1]
+			}
+			line++
+		case lex.Name:
+			prop = parse.Property{Name: item.Value, Params: make(parse.Parameters)}
+		case lex.ParamName:
+			param = item.Value
+			prop.Params[param] = nil
+		case lex.ParamValue:
+			prop.Params[param] = append(prop.Params[param], item.Value)
+		case lex.Value:
+			prop.Value = item.Value
+			if len(stack) > 0 {
+				stack[len(stack)-1].props[prop.Name] = true
+			}
+			l.lintProperty(line, prop)
+			line += 1 + strings.Count(item.Raw, "\n")
+		}
+	}
+}
+
+func (l *linter) lintComponent(comp lintComponent) {
+	if comp.name != "VEVENT" {
+		return
+	}
+
+	for _, name := range []string{"UID", "DTSTAMP"} {
+		if !comp.props[name] {
+			l.addf(comp.line, SeverityWarning, "%s has no %s", comp.name, name)
+		}
+	}
+}
+
+func (l *linter) lintProperty(line int, prop parse.Property) {
+	if !lintTimeProperties[prop.Name] {
+		return
+	}
+
+	if typ := prop.ValueType(); typ != "DATE" && typ != "DATE-TIME" {
+		return
+	}
+
+	if _, err := parse.Time(prop); err != nil {
+		l.addf(line, SeverityError, "invalid %s value %q: %v", prop.Name, prop.Value, err)
+		return
+	}
+
+	// https://tools.ietf.org/html/rfc5545#section-3.8.7.2
+	if prop.Name == "DTSTAMP" && !strings.HasSuffix(prop.Value, "Z") {
+		l.addf(line, SeverityWarning, "DTSTAMP value %q is not in UTC", prop.Value)
+	}
+}
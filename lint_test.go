@@ -0,0 +1,79 @@
+package ical_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bounoable/ical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint(t *testing.T) {
+	f, err := os.Open("testdata/lint.ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	issues := ical.Lint(f)
+	if !assert.Len(t, issues, 5) {
+		return
+	}
+
+	expected := []struct {
+		line     int
+		severity ical.Severity
+		message  string
+	}{
+		{7, ical.SeverityError, `invalid DTSTART value "20201301T100000Z"`},
+		{10, ical.SeverityWarning, "VEVENT has no UID"},
+		{10, ical.SeverityWarning, "VEVENT has no DTSTAMP"},
+		{12, ical.SeverityWarning, "line 12 has 107 octets"},
+		{14, ical.SeverityWarning, "unknown component VTHING"},
+	}
+
+	for i, want := range expected {
+		assert.Equal(t, want.line, issues[i].Line)
+		assert.Equal(t, want.severity, issues[i].Severity)
+		assert.Contains(t, issues[i].Message, want.message)
+	}
+}
+
+func TestLint_valid(t *testing.T) {
+	issues := ical.Lint(strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20200101T000000Z\r\nEND:VEVENT\r\nEND:VCALENDAR"))
+	assert.Empty(t, issues)
+}
+
+func TestLint_localDTSTAMP(t *testing.T) {
+	issues := ical.Lint(strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20200101T000000\r\nEND:VEVENT\r\nEND:VCALENDAR"))
+	if assert.Len(t, issues, 1) {
+		assert.Equal(t, 4, issues[0].Line)
+		assert.Equal(t, ical.SeverityWarning, issues[0].Severity)
+		assert.Equal(t, `DTSTAMP value "20200101T000000" is not in UTC`, issues[0].Message)
+	}
+}
+
+func TestLint_foldedLines(t *testing.T) {
+	issues := ical.Lint(strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:1\r\nDESCRIPTION:Foo\r\n bar\r\n baz\r\nDTSTAMP:20201301T000000Z\r\nEND:VEVENT\r\nEND:VCALENDAR"))
+	if assert.Len(t, issues, 1) {
+		assert.Equal(t, 7, issues[0].Line)
+		assert.Equal(t, ical.SeverityError, issues[0].Severity)
+	}
+}
+
+func TestLint_lexError(t *testing.T) {
+	issues := ical.Lint(strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:1\r\nSUMMARY\r\nEND:VEVENT\r\nEND:VCALENDAR"))
+	if assert.Len(t, issues, 1) {
+		assert.Equal(t, 4, issues[0].Line)
+		assert.Equal(t, ical.SeverityError, issues[0].Severity)
+	}
+}
+
+func TestLint_parseError(t *testing.T) {
+	issues := ical.Lint(strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20200101T000000Z\r\nEND:VCALENDAR"))
+	if assert.Len(t, issues, 1) {
+		assert.Equal(t, 0, issues[0].Line)
+		assert.Equal(t, ical.SeverityError, issues[0].Severity)
+	}
+}
@@ -2,6 +2,7 @@
 package ical
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"os"
@@ -14,23 +15,26 @@ import (
 // Calendar is a parsed iCalendar.
 type Calendar parse.Calendar
 
-// Parse parses the iCalendar from r.
+// Parse parses the iCalendar from r. If parsing fails, the partial calendar
+// that has been parsed up to the error is returned together with the error
+// (see parse.Parser.Parse).
 func Parse(r io.Reader, opts ...Option) (Calendar, error) {
-	var cfg config
+	cfg := config{ctx: context.Background()}
 	for _, opt := range opts {
 		opt(&cfg)
 	}
 
+	// the lexer is canceled when the parser returns, so that it stops lexing
+	// the rest of the input if the parser stops early (e.g. parse.HeaderOnly)
+	ctx, cancel := context.WithCancel(cfg.ctx)
+	defer cancel()
+
 	cal, err := parse.Items(
-		lex.Reader(r, cfg.lexerOptions...),
+		lex.Reader(r, append([]lex.Option{lex.Context(ctx)}, cfg.lexerOptions...)...),
 		cfg.parserOptions...,
 	)
 
-	if err != nil {
-		return Calendar{}, err
-	}
-
-	return Calendar(cal), nil
+	return Calendar(cal), err
 }
 
 // ParseFile parses the iCalendar from the file at filepath.
@@ -48,6 +52,22 @@ func ParseText(text string, opts ...Option) (Calendar, error) {
 	return Parse(strings.NewReader(text), opts...)
 }
 
+// ParseBytes parses the iCalendar from b.
+func ParseBytes(b []byte, opts ...Option) (Calendar, error) {
+	return Parse(bytes.NewReader(b), opts...)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It parses the iCalendar
+// from text into cal.
+func (cal *Calendar) UnmarshalText(text []byte) error {
+	parsed, err := ParseBytes(text)
+	if err != nil {
+		return err
+	}
+	*cal = parsed
+	return nil
+}
+
 // Option is a lex/parse option.
 type Option func(*config)
 
@@ -68,12 +88,13 @@ func ParseWith(opts ...parse.Option) Option {
 // Context adds a context to the lexer & parser.
 func Context(ctx context.Context) Option {
 	return func(cfg *config) {
-		LexWith(lex.Context(ctx))(cfg)
+		cfg.ctx = ctx
 		ParseWith(parse.Context(ctx))(cfg)
 	}
 }
 
 type config struct {
+	ctx           context.Context
 	lexerOptions  []lex.Option
 	parserOptions []parse.Option
 }
@@ -0,0 +1,87 @@
+package parse
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Period is a period of time (https://tools.ietf.org/html/rfc5545#section-3.3.9)
+// from Start (inclusive) to End (exclusive).
+type Period struct {
+	Start time.Time
+	End   time.Time
+	// Duration of a period of the form "start/duration", e.g. "19970308T230000Z/PT1H".
+	// Zero for periods with an explicit End.
+	Duration time.Duration
+}
+
+// BusyPeriods returns the periods within [from, to) in which the events of the
+// calendar are busy, e.g. for a free/busy lookup. Recurring events are expanded
+// (see Expand). Events with TRANSP:TRANSPARENT or STATUS:CANCELLED and events
+// without a duration are not busy. The busy periods of overlapping and adjacent
+// events are merged, so the returned periods are sorted and don't overlap.
+// Periods that begin before from or end after to are truncated to [from, to).
+func (cal Calendar) BusyPeriods(from, to time.Time) []Period {
+	// occurrences that begin before from may still be busy within [from, to)
+	var lookback time.Duration
+	for _, evt := range cal.Events {
+		if d := evt.ComputedDuration(); d > lookback {
+			lookback = d
+		}
+	}
+
+	var periods []Period
+	for _, evt := range cal.Expand(from.Add(-lookback), to).Events {
+		if !evt.isBusy() || evt.End.IsZero() || !evt.End.After(from) || !evt.Start.Before(to) {
+			continue
+		}
+
+		period := Period{Start: evt.Start, End: evt.End}
+		if period.Start.Before(from) {
+			period.Start = from
+		}
+		if period.End.After(to) {
+			period.End = to
+		}
+		if period.End.After(period.Start) {
+			periods = append(periods, period)
+		}
+	}
+
+	return mergePeriods(periods)
+}
+
+// isBusy determines if the event is neither transparent nor cancelled.
+func (evt Event) isBusy() bool {
+	if prop, ok := evt.Property("TRANSP"); ok && strings.EqualFold(prop.Value, "TRANSPARENT") {
+		return false
+	}
+	if prop, ok := evt.Property("STATUS"); ok && strings.EqualFold(prop.Value, "CANCELLED") {
+		return false
+	}
+	return true
+}
+
+// mergePeriods sorts periods and merges overlapping and adjacent periods.
+func mergePeriods(periods []Period) []Period {
+	if len(periods) == 0 {
+		return nil
+	}
+
+	sort.Slice(periods, func(a, b int) bool { return periods[a].Start.Before(periods[b].Start) })
+
+	merged := periods[:1]
+	for _, period := range periods[1:] {
+		last := &merged[len(merged)-1]
+		if period.Start.After(last.End) {
+			merged = append(merged, period)
+			continue
+		}
+		if period.End.After(last.End) {
+			last.End = period.End
+		}
+	}
+
+	return merged
+}
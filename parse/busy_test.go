@@ -0,0 +1,69 @@
+package parse_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bounoable/ical/lex"
+	"github.com/bounoable/ical/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalendar_BusyPeriods(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:overnight
+DTSTART:20191231T220000Z
+DTEND:20200101T090000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:first
+DTSTART:20200101T100000Z
+DTEND:20200101T113000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:overlapping
+DTSTART:20200101T110000Z
+DTEND:20200101T120000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:adjacent
+DTSTART:20200101T120000Z
+DTEND:20200101T123000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:transparent
+DTSTART:20200101T130000Z
+DTEND:20200101T140000Z
+TRANSP:TRANSPARENT
+END:VEVENT
+BEGIN:VEVENT
+UID:cancelled
+DTSTART:20200101T140000Z
+DTEND:20200101T150000Z
+STATUS:CANCELLED
+END:VEVENT
+BEGIN:VEVENT
+UID:daily
+DTSTART:20200101T160000Z
+DTEND:20200101T170000Z
+RRULE:FREQ=DAILY;COUNT=3
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(day, hour, min int) time.Time { return time.Date(2020, time.January, day, hour, min, 0, 0, time.UTC) }
+
+	assert.Equal(t, []parse.Period{
+		{Start: at(1, 0, 0), End: at(1, 9, 0)},
+		{Start: at(1, 10, 0), End: at(1, 12, 30)},
+		{Start: at(1, 16, 0), End: at(1, 17, 0)},
+		{Start: at(2, 16, 0), End: at(2, 16, 30)},
+	}, cal.BusyPeriods(at(1, 0, 0), at(2, 16, 30)))
+
+	assert.Empty(t, cal.BusyPeriods(at(5, 0, 0), at(6, 0, 0)))
+}
@@ -1,6 +1,8 @@
 package parse
 
 import (
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -16,7 +18,24 @@ type Calendar struct {
 	Calscale string
 	// iCalendar object method (https://tools.ietf.org/html/rfc5545#section-3.7.2)
 	Method string
-	Events []Event
+	// RelatedCalendarID is the value of the X-WR-RELCALID property, a globally
+	// unique identifier of the calendar that stays the same across refreshes of
+	// a feed. Other X-WR-* properties are available through HeaderProperty.
+	RelatedCalendarID string
+	Events            []Event
+	// Components that have no dedicated type, e.g. VTIMEZONEs
+	Components []Component
+	// Timezones are the locations of the VTIMEZONE components by TZID
+	Timezones map[string]*time.Location
+	// Errors of events that have been skipped because of the SkipErrors option
+	// and the ErrDuplicateUID errors of the DetectDuplicateUIDs option
+	Errors Errors
+	// Truncated reports whether events have been dropped because the calendar
+	// has more events than allowed by the MaxEvents option.
+	Truncated bool
+	// Warnings are the non-fatal problems of the input, e.g. lines that
+	// exceed the limit of the lex.MaxOctetsPerLine option
+	Warnings []string
 }
 
 // Event is a parsed iCalendar event.
@@ -30,13 +49,243 @@ type Event struct {
 	End         time.Time
 	Summary     string
 	Description string
+	// SummaryLanguage is the LANGUAGE parameter (https://tools.ietf.org/html/rfc5545#section-3.2.10)
+	// of the summary, e.g. "de".
+	SummaryLanguage string
+	// DescriptionLanguage is the LANGUAGE parameter of the description.
+	DescriptionLanguage string
+	// Summaries are the summaries of all SUMMARY properties, e.g. the translations
+	// of the summary in different languages. Summary is the summary without a
+	// LANGUAGE parameter or, if every summary has one, the first summary.
+	Summaries []LocalizedText
+	// Descriptions are the descriptions of all DESCRIPTION properties. Description
+	// is chosen like Summary.
+	Descriptions []LocalizedText
+	// DescriptionAltRep is the URI of an alternate representation of the description
+	// (https://tools.ietf.org/html/rfc5545#section-3.2.1), e.g. an HTML version.
+	DescriptionAltRep string
+	// Location (https://tools.ietf.org/html/rfc5545#section-3.8.1.7)
+	Location string
+	// LocationAltRep is the URI of an alternate representation of the location.
+	LocationAltRep string
+	// Geo (https://tools.ietf.org/html/rfc5545#section-3.8.1.6) is the global
+	// position of the event. Geo is nil if the event has no GEO property.
+	Geo *GeoPoint
+	// URL (https://tools.ietf.org/html/rfc5545#section-3.8.4.6) of the event
+	URL string
+	// Categories (https://tools.ietf.org/html/rfc5545#section-3.8.1.2) of all CATEGORIES properties
+	Categories []string
+	// Conferences (https://tools.ietf.org/html/rfc7986#section-5.11)
+	Conferences []Conference
+	// Organizer of the event. The Address is empty if the event has no ORGANIZER.
+	Organizer Organizer
+	// Attendees (https://tools.ietf.org/html/rfc5545#section-3.8.4.1)
+	Attendees []Attendee
+	// Attachments (https://tools.ietf.org/html/rfc5545#section-3.8.1.1)
+	Attachments []Attachment
+	// Request statuses (https://tools.ietf.org/html/rfc5545#section-3.8.8.3) of all REQUEST-STATUS properties
+	RequestStatus []RequestStatus
+	// Recurrence Rule (https://tools.ietf.org/html/rfc5545#section-3.8.5.3)
+	// Recurrence is the first rule of RecurrenceRules.
+	Recurrence      *RecurrenceRule
+	RecurrenceRules []RecurrenceRule
+	// Exception Rules (https://tools.ietf.org/html/rfc2445#section-4.8.5.2)
+	ExceptionRules []RecurrenceRule
+	// Recurrence Date-Times (https://tools.ietf.org/html/rfc5545#section-3.8.5.2)
+	RDates []time.Time
+	// Exception Date-Times (https://tools.ietf.org/html/rfc5545#section-3.8.5.1)
+	ExDates []time.Time
+	// Components that have no dedicated type
+	Components []Component
+	// Recurrence ID (https://tools.ietf.org/html/rfc5545#section-3.8.4.4)
+	// RecurrenceID is the zero Time if the event is not an occurrence of a recurring event.
+	RecurrenceID time.Time
+	// RecurrenceRange is the RANGE parameter of the RECURRENCE-ID property
+	// (https://tools.ietf.org/html/rfc5545#section-3.2.13). If it is "THISANDFUTURE",
+	// the event overrides the occurrence at RecurrenceID and all following occurrences.
+	RecurrenceRange string
+}
+
+// Conference is information for accessing a conferencing system.
+type Conference struct {
+	// URI to access the conference
+	URI string
+	// Features of the conference (e.g. AUDIO, VIDEO, CHAT)
+	Features []string
+	// Label of the conference
+	Label string
+}
+
+// Attendee is a participant of an event.
+type Attendee struct {
+	// Address is the calendar user address (CAL-ADDRESS), e.g. "mailto:jdoe@example.com".
+	// Addresses of other URI schemes, e.g. "urn:uuid:..." or "https://...", are kept as they are.
+	Address string
+	// Email is the email address of a "mailto:" Address. Email is empty if the
+	// Address has another scheme.
+	Email string
+	// CommonName is the CN parameter.
+	CommonName string
+	// Role is the ROLE parameter, e.g. "REQ-PARTICIPANT".
+	Role string
+	// PartStat is the participation status, e.g. "ACCEPTED".
+	PartStat string
+	// RSVP is nil if the attendee has no RSVP parameter or if its value is
+	// neither TRUE nor FALSE.
+	RSVP *bool
+	// ScheduleAgent is the SCHEDULE-AGENT parameter (https://tools.ietf.org/html/rfc6638#section-7.1),
+	// e.g. "SERVER" or "CLIENT".
+	ScheduleAgent string
+	// ScheduleStatus are the status codes of the SCHEDULE-STATUS parameter
+	// (https://tools.ietf.org/html/rfc6638#section-7.3), e.g. "2.0".
+	ScheduleStatus []string
+}
+
+// Organizer is the parsed ORGANIZER (https://tools.ietf.org/html/rfc5545#section-3.8.4.3) of an event.
+type Organizer struct {
+	// Address is the calendar user address (CAL-ADDRESS), e.g. "mailto:jdoe@example.com".
+	// Addresses of other URI schemes, e.g. "urn:uuid:..." or "https://...", are kept as they are.
+	Address string
+	// Email is the email address of a "mailto:" Address. Email is empty if the
+	// Address has another scheme.
+	Email string
+	// CommonName is the CN parameter.
+	CommonName string
+	// ScheduleAgent is the SCHEDULE-AGENT parameter, e.g. "SERVER" or "CLIENT".
+	ScheduleAgent string
+	// ScheduleStatus are the status codes of the SCHEDULE-STATUS parameter.
+	ScheduleStatus []string
+}
+
+// LocalizedText is a TEXT value and its LANGUAGE parameter
+// (https://tools.ietf.org/html/rfc5545#section-3.2.10).
+type LocalizedText struct {
+	Value string
+	// Language of the value, e.g. "de". Empty if the value has no LANGUAGE parameter.
+	Language string
+}
+
+// Attachment is a document that is associated with an event. Either URI or
+// Data is set, depending on whether the attachment is referenced or inlined.
+type Attachment struct {
+	// URI of a referenced attachment
+	URI string
+	// Data is the decoded content of an inline (BASE64 encoded) attachment
+	// or nil if the value is not valid BASE64.
+	Data []byte
+	// FormatType is the FMTTYPE parameter, e.g. "application/pdf".
+	FormatType string
+	// Filename is the FILENAME parameter (https://tools.ietf.org/html/rfc8607#section-4.2).
+	Filename string
+	// Size is the SIZE parameter in octets (https://tools.ietf.org/html/rfc8607#section-4.3)
+	// or 0 if the attachment has no valid SIZE parameter.
+	Size int64
+	// ManagedID is the MANAGED-ID parameter (https://tools.ietf.org/html/rfc8607#section-4.1).
+	ManagedID string
+}
+
+// RequestStatus is the status code returned for a scheduling request.
+type RequestStatus struct {
+	// Code is the hierarchical status code, e.g. "2.0".
+	Code string
+	// Description of the status, e.g. "Success".
+	Description string
+	// ExtraData is the optional data that caused the status, e.g. the offending property value.
+	ExtraData string
+}
+
+// GeoPoint is a global position in degrees.
+type GeoPoint struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Component is a parsed iCalendar component that has no dedicated type.
+type Component struct {
+	Name       string
+	Properties []Property
+	Components []Component
+}
+
+// Property returns the Property with the given name.
+func (comp Component) Property(name string) (Property, bool) {
+	for _, prop := range comp.Properties {
+		if prop.Name == name {
+			return prop, true
+		}
+	}
+	return Property{}, false
 }
 
 // Alarm is a parsed iCalendar alarm.
 type Alarm struct {
 	Properties []Property
-	Action     string
-	Trigger    string
+	// Action of the alarm. Unknown actions (e.g. "NONE" (https://tools.ietf.org/html/rfc9074#section-7))
+	// are kept as they are.
+	Action string
+	// Trigger (https://tools.ietf.org/html/rfc5545#section-3.8.6.3) of the alarm,
+	// or the zero Trigger if the TRIGGER value is invalid
+	Trigger Trigger
+	// Proximity (https://tools.ietf.org/html/rfc9074#section-8.1), e.g. "ARRIVE" or "DEPART"
+	Proximity string
+	// Acknowledged (https://tools.ietf.org/html/rfc9074#section-6) is the zero Time
+	// if the alarm has not been acknowledged or if the ACKNOWLEDGED value is invalid.
+	Acknowledged time.Time
+	// Repeat (https://tools.ietf.org/html/rfc5545#section-3.8.6.2) is the number
+	// of times the alarm repeats after the initial trigger. Repeat is 0 if the
+	// REPEAT value is invalid or greater than 1000.
+	Repeat int
+	// Duration (https://tools.ietf.org/html/rfc5545#section-3.8.2.5) is the delay
+	// between the repetitions of the alarm, or 0 if the DURATION value is invalid.
+	Duration time.Duration
+	// Components of the alarm, e.g. the VLOCATIONs of a proximity alarm
+	Components []Component
+}
+
+// Trigger is the parsed TRIGGER of an alarm. A trigger is either absolute
+// (VALUE=DATE-TIME) or relative to the start or end of the event.
+type Trigger struct {
+	// Time of an absolute trigger. Zero for relative triggers.
+	Time time.Time
+	// Duration of a relative trigger. Negative durations trigger before the
+	// start or end of the event.
+	Duration time.Duration
+	// Related is the RELATED parameter of a relative trigger, "START" (default) or "END".
+	Related string
+}
+
+// IsAbsolute determines if the trigger is an absolute trigger.
+func (t Trigger) IsAbsolute() bool {
+	return !t.Time.IsZero()
+}
+
+// TriggerTime returns the time at which the alarm of evt triggers. Relative
+// triggers are resolved against the Start or the End of evt.
+func (alarm Alarm) TriggerTime(evt Event) time.Time {
+	if alarm.Trigger.IsAbsolute() {
+		return alarm.Trigger.Time
+	}
+
+	if alarm.Trigger.Related == "END" {
+		return evt.Start.Add(evt.ComputedDuration()).Add(alarm.Trigger.Duration)
+	}
+	return evt.Start.Add(alarm.Trigger.Duration)
+}
+
+// FireTimes returns the times at which the alarm of evt fires: the trigger
+// time (see TriggerTime), followed by Repeat repetitions that are Duration
+// apart. The repetitions are ignored if Repeat or Duration is not positive.
+func (alarm Alarm) FireTimes(evt Event) []time.Time {
+	t := alarm.TriggerTime(evt)
+	if alarm.Repeat <= 0 || alarm.Duration <= 0 {
+		return []time.Time{t}
+	}
+
+	var times []time.Time
+	for i := 0; i <= alarm.Repeat; i++ {
+		times = append(times, t.Add(time.Duration(i)*alarm.Duration))
+	}
+	return times
 }
 
 // Property is an iCalendar property / content-line.
@@ -44,6 +293,83 @@ type Property struct {
 	Name   string
 	Params Parameters
 	Value  string
+	// Raw is the original content line of the property if it has been lexed
+	// with the lex.KeepRaw option. Clear Raw when modifying a property, so
+	// that encoders don't write the original line (see encode.PreserveRaw).
+	Raw string
+	// ParamOrder are the names of the parameters in the order in which they
+	// have been parsed with the KeepParamOrder option (see encode.PreserveParamOrder).
+	ParamOrder []string
+}
+
+// ValueType returns the value type of the property (https://tools.ietf.org/html/rfc5545#section-3.2.20).
+// If the property has no VALUE parameter, the default value type of the property is returned.
+func (prop Property) ValueType() string {
+	if typ, ok := prop.Params.First("VALUE"); ok {
+		return strings.ToUpper(typ)
+	}
+
+	if typ, ok := defaultValueTypes[prop.Name]; ok {
+		return typ
+	}
+
+	return "TEXT"
+}
+
+// defaultValueTypes are the value types of properties that have no VALUE parameter.
+// Properties that are not listed here default to TEXT.
+var defaultValueTypes = map[string]string{
+	"ATTACH":           "URI",
+	"ATTENDEE":         "CAL-ADDRESS",
+	"COMPLETED":        "DATE-TIME",
+	"CONFERENCE":       "URI",
+	"CREATED":          "DATE-TIME",
+	"DTEND":            "DATE-TIME",
+	"DTSTAMP":          "DATE-TIME",
+	"DTSTART":          "DATE-TIME",
+	"DUE":              "DATE-TIME",
+	"DURATION":         "DURATION",
+	"EXDATE":           "DATE-TIME",
+	"EXRULE":           "RECUR",
+	"FREEBUSY":         "PERIOD",
+	"GEO":              "FLOAT",
+	"IMAGE":            "URI",
+	"LAST-MODIFIED":    "DATE-TIME",
+	"ORGANIZER":        "CAL-ADDRESS",
+	"PERCENT-COMPLETE": "INTEGER",
+	"PRIORITY":         "INTEGER",
+	"RDATE":            "DATE-TIME",
+	"RECURRENCE-ID":    "DATE-TIME",
+	"REPEAT":           "INTEGER",
+	"RRULE":            "RECUR",
+	"SEQUENCE":         "INTEGER",
+	"SOURCE":           "URI",
+	"TRIGGER":          "DURATION",
+	"TZOFFSETFROM":     "UTC-OFFSET",
+	"TZOFFSETTO":       "UTC-OFFSET",
+	"TZURL":            "URI",
+	"URL":              "URI",
+}
+
+// isDateValue determines if prop has a DATE value. If prop has no VALUE parameter,
+// the value type is inferred from the length of the value, because many
+// iCalendars omit the VALUE=DATE parameter of DATE values.
+func isDateValue(prop Property) bool {
+	if _, ok := prop.Params.First("VALUE"); ok {
+		return prop.ValueType() == "DATE"
+	}
+	// year-month values are parsed as DATE values by the LenientDates option
+	return len(prop.Value) == len(layoutDate) || isYearMonth(prop.Value)
+}
+
+// isTimeValue determines if prop has a DATE or DATE-TIME value.
+func isTimeValue(prop Property) bool {
+	switch prop.ValueType() {
+	case "DATE", "DATE-TIME":
+		return true
+	default:
+		return false
+	}
 }
 
 // Parameters are the parameters of a Property.
@@ -65,6 +391,199 @@ func (params Parameters) Contains(name string, val string) bool {
 	return false
 }
 
+// First returns the first value of the parameter with the given name.
+// Surrounding double quotes are removed from the returned value.
+func (params Parameters) First(name string) (string, bool) {
+	vals := params[name]
+	if len(vals) == 0 {
+		return "", false
+	}
+	return unquote(vals[0]), true
+}
+
+// Values returns the unquoted values of the parameter with the given name.
+func (params Parameters) Values(name string) []string {
+	vals := params[name]
+	if len(vals) == 0 {
+		return nil
+	}
+
+	unquoted := make([]string, len(vals))
+	for i, val := range vals {
+		unquoted[i] = unquote(val)
+	}
+	return unquoted
+}
+
+func (params Parameters) clone() Parameters {
+	if params == nil {
+		return nil
+	}
+
+	clone := make(Parameters, len(params))
+	for name, vals := range params {
+		clone[name] = append([]string(nil), vals...)
+	}
+	return clone
+}
+
+func unquote(val string) string {
+	if len(val) >= 2 && strings.HasPrefix(val, `"`) && strings.HasSuffix(val, `"`) {
+		return val[1 : len(val)-1]
+	}
+	return val
+}
+
+// HeaderProperty returns the first calendar property with the given name.
+// Names are compared case-insensitively.
+func (cal Calendar) HeaderProperty(name string) (Property, bool) {
+	for _, prop := range cal.Properties {
+		if strings.EqualFold(prop.Name, name) {
+			return prop, true
+		}
+	}
+	return Property{}, false
+}
+
+// HeaderProperties returns all calendar properties with the given name.
+// Names are compared case-insensitively.
+func (cal Calendar) HeaderProperties(name string) []Property {
+	var props []Property
+	for _, prop := range cal.Properties {
+		if strings.EqualFold(prop.Name, name) {
+			props = append(props, prop)
+		}
+	}
+	return props
+}
+
+// UnknownProperties returns the properties of the calendar, its events and their
+// alarms that are not mapped to a typed field by the parser, e.g. vendor
+// extensions ("X-" properties). Properties that are mapped by a FieldMapper
+// but have no default handler are also returned.
+func (cal Calendar) UnknownProperties() []Property {
+	var props []Property
+	for _, prop := range cal.Properties {
+		if !calendarFields[prop.Name] {
+			props = append(props, prop)
+		}
+	}
+
+	for _, evt := range cal.Events {
+		for _, prop := range evt.Properties {
+			if _, ok := defaultEventFields[prop.Name]; !ok && !implicitEventFields[prop.Name] {
+				props = append(props, prop)
+			}
+		}
+
+		for _, alarm := range evt.Alarms {
+			for _, prop := range alarm.Properties {
+				if !alarmFields[prop.Name] {
+					props = append(props, prop)
+				}
+			}
+		}
+	}
+
+	return props
+}
+
+// Span returns the earliest Start and the latest End of all events in the calendar.
+// End times are exclusive, so all-day events end at midnight of the following day.
+// ok is false if the calendar has no events.
+func (cal Calendar) Span() (earliest, latest time.Time, ok bool) {
+	for i, evt := range cal.Events {
+		end := evt.End
+		if end.Before(evt.Start) {
+			end = evt.Start
+		}
+
+		if i == 0 || evt.Start.Before(earliest) {
+			earliest = evt.Start
+		}
+
+		if i == 0 || end.After(latest) {
+			latest = end
+		}
+	}
+	return earliest, latest, len(cal.Events) > 0
+}
+
+// VersionRange returns the minimum and maximum iCalendar version that is required
+// to interpret the calendar (https://tools.ietf.org/html/rfc5545#section-3.7.4).
+// If the VERSION property is a single version, min and max are equal.
+func (cal Calendar) VersionRange() (min, max string) {
+	if i := strings.IndexByte(cal.Version, ';'); i >= 0 {
+		return cal.Version[:i], cal.Version[i+1:]
+	}
+	return cal.Version, cal.Version
+}
+
+// IsEmpty determines if the calendar has no components, neither events nor
+// components without a dedicated type. Use IsEmpty instead of comparing Events
+// to nil, because Events may be nil or an empty slice.
+func (cal Calendar) IsEmpty() bool {
+	return len(cal.Events) == 0 && len(cal.Components) == 0
+}
+
+// EventsWithCategory returns the events that have the given category.
+// Categories are compared case-insensitively.
+func (cal Calendar) EventsWithCategory(cat string) []Event {
+	var events []Event
+	for _, evt := range cal.Events {
+		if evt.HasCategory(cat) {
+			events = append(events, evt)
+		}
+	}
+	return events
+}
+
+// AddEvent adds evt to the events of the calendar.
+func (cal *Calendar) AddEvent(evt Event) {
+	cal.Events = append(cal.Events, evt)
+}
+
+// RemoveEventByUID removes all events with the given UID from the calendar,
+// including the overrides of a recurring event (events with a RECURRENCE-ID).
+// It returns whether any event has been removed. The UID of an event is its
+// UID field or, if the field is empty, the value of its UID property.
+func (cal *Calendar) RemoveEventByUID(uid string) bool {
+	// the events are copied instead of filtered in place, because their
+	// backing array may be shared with a copy of the calendar
+	events := make([]Event, 0, len(cal.Events))
+	for _, evt := range cal.Events {
+		if evt.uid() != uid {
+			events = append(events, evt)
+		}
+	}
+
+	if len(events) == len(cal.Events) {
+		return false
+	}
+	cal.Events = events
+
+	return true
+}
+
+func (evt Event) uid() string {
+	if evt.UID != "" {
+		return evt.UID
+	}
+	prop, _ := evt.Property("UID")
+	return prop.Value
+}
+
+// HasCategory determines if the event has the given category.
+// Categories are compared case-insensitively.
+func (evt Event) HasCategory(cat string) bool {
+	for _, c := range evt.Categories {
+		if strings.EqualFold(c, cat) {
+			return true
+		}
+	}
+	return false
+}
+
 // Property returns the Property with the given name.
 func (evt Event) Property(name string) (Property, bool) {
 	for _, prop := range evt.Properties {
@@ -75,8 +594,169 @@ func (evt Event) Property(name string) (Property, bool) {
 	return Property{}, false
 }
 
-func (evt *Event) finalize() error {
-	if err := evt.applyDuration(); err != nil {
+// ComputedDuration returns the duration between the Start and the End of the
+// event, regardless of whether the end is given by a DTEND or DURATION property
+// or is implicit. The End of all-day events (DATE values) is exclusive, so an
+// event from January 1 to January 4 lasts 72 hours. All-day events always last
+// whole days, even if they span a daylight saving time transition. It returns 0
+// if the event has no End.
+func (evt Event) ComputedDuration() time.Duration {
+	if evt.End.IsZero() || evt.Start.IsZero() {
+		return 0
+	}
+
+	if dtstart, ok := evt.Property("DTSTART"); ok && isDateValue(dtstart) {
+		return dateOf(evt.End).Sub(dateOf(evt.Start))
+	}
+
+	return evt.End.Sub(evt.Start)
+}
+
+// Days returns the dates that an all-day event covers, from the date of the
+// Start up to the date of the End (exclusive), at midnight in the location of
+// the Start. It returns nil for events that are not all-day events (DTSTART
+// with a DATE value).
+func (evt Event) Days() []time.Time {
+	dtstart, ok := evt.Property("DTSTART")
+	if !ok || !isDateValue(dtstart) || evt.Start.IsZero() {
+		return nil
+	}
+
+	n := int(evt.ComputedDuration() / (24 * time.Hour))
+	if n < 1 {
+		n = 1
+	}
+
+	days := make([]time.Time, n)
+	for i := range days {
+		days[i] = time.Date(evt.Start.Year(), evt.Start.Month(), evt.Start.Day()+i, 0, 0, 0, 0, evt.Start.Location())
+	}
+	return days
+}
+
+// dateOf returns the date of t at midnight UTC.
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// SetStart sets the Start of the event and updates the DTSTART property accordingly,
+// so that the change is preserved when the event is encoded. If allDay is true,
+// the start is set to the date of t and written as a DATE value.
+func (evt *Event) SetStart(t time.Time, allDay bool) {
+	evt.Start = evt.setTime("DTSTART", t, allDay)
+}
+
+// SetEnd sets the End of the event and updates the DTEND property accordingly,
+// so that the change is preserved when the event is encoded. A DURATION property
+// is removed, because an event cannot have both a DTEND and a DURATION.
+// If allDay is true, the end is set to the date of t and written as a DATE value.
+func (evt *Event) SetEnd(t time.Time, allDay bool) {
+	evt.End = evt.setTime("DTEND", t, allDay)
+	evt.removeProperties("DURATION")
+}
+
+// setTime sets the value of the date / datetime property with the given name to t
+// and returns the time that is represented by the property.
+func (evt *Event) setTime(name string, t time.Time, allDay bool) time.Time {
+	prop := Property{Name: name}
+	for _, p := range evt.Properties {
+		if p.Name == name {
+			prop.Params = p.Params.clone()
+			break
+		}
+	}
+	if prop.Params == nil {
+		prop.Params = make(Parameters)
+	}
+	delete(prop.Params, "VALUE")
+	delete(prop.Params, "TZID")
+
+	switch {
+	case allDay:
+		t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		prop.Params["VALUE"] = []string{"DATE"}
+		prop.Value = t.Format(layoutDate)
+	case t.Location() == time.UTC:
+		prop.Value = t.Format(layoutDateTimeUTC)
+	case t.Location() == time.Local:
+		prop.Value = t.Format(layoutDateTimeLocal)
+	default:
+		prop.Params["TZID"] = []string{t.Location().String()}
+		prop.Value = t.Format(layoutDateTimeLocal)
+	}
+
+	// Copy the properties instead of writing into the existing slice, because
+	// its backing array may be shared with a copy of the event.
+	props := make([]Property, 0, len(evt.Properties)+1)
+	replaced := false
+	for _, p := range evt.Properties {
+		if p.Name == name && !replaced {
+			p = prop
+			replaced = true
+		}
+		props = append(props, p)
+	}
+	if !replaced {
+		props = append(props, prop)
+	}
+	evt.Properties = props
+
+	return t
+}
+
+func (evt *Event) removeProperties(name string) {
+	props := make([]Property, 0, len(evt.Properties))
+	for _, prop := range evt.Properties {
+		if prop.Name != name {
+			props = append(props, prop)
+		}
+	}
+	evt.Properties = props
+}
+
+// SetPartStat sets the participation status (https://tools.ietf.org/html/rfc5545#section-3.2.12)
+// of the attendee with the given email address, e.g. to "ACCEPTED" or "DECLINED".
+// SetPartStat updates the PARTSTAT parameter of the attendee's ATTENDEE property,
+// so the change is preserved when the event is encoded.
+func (evt *Event) SetPartStat(email, status string) error {
+	for i, prop := range evt.Properties {
+		if prop.Name != "ATTENDEE" || !strings.EqualFold(calAddressEmail(prop.Value), email) {
+			continue
+		}
+
+		// copy the properties, params and attendees instead of writing into
+		// them, because they may be shared with a copy of the event
+		prop.Params = prop.Params.clone()
+		if prop.Params == nil {
+			prop.Params = make(Parameters)
+		}
+		prop.Params["PARTSTAT"] = []string{status}
+		prop.Raw = ""
+		evt.Properties = append([]Property(nil), evt.Properties...)
+		evt.Properties[i] = prop
+
+		evt.Attendees = append([]Attendee(nil), evt.Attendees...)
+		for j, att := range evt.Attendees {
+			if strings.EqualFold(calAddressEmail(att.Address), email) {
+				evt.Attendees[j].PartStat = status
+			}
+		}
+
+		return nil
+	}
+	return fmt.Errorf("attendee %q not found", email)
+}
+
+// calAddressEmail returns the email address of a "mailto:" calendar user address.
+func calAddressEmail(addr string) string {
+	if len(addr) >= len("mailto:") && strings.EqualFold(addr[:len("mailto:")], "mailto:") {
+		return addr[len("mailto:"):]
+	}
+	return addr
+}
+
+func (evt *Event) finalize(lenient bool) error {
+	if err := evt.applyDuration(lenient); err != nil {
 		return err
 	}
 
@@ -85,7 +765,7 @@ func (evt *Event) finalize() error {
 	return nil
 }
 
-func (evt *Event) applyDuration() error {
+func (evt *Event) applyDuration(lenient bool) error {
 	if _, ok := evt.Property("DTEND"); ok {
 		return nil
 	}
@@ -95,7 +775,7 @@ func (evt *Event) applyDuration() error {
 		return nil
 	}
 
-	dur, err := parseDuration(prop.Value)
+	dur, err := parseDuration(prop.Value, lenient)
 	if err != nil {
 		return err
 	}
@@ -110,9 +790,7 @@ func (evt *Event) applyImplicitOneDayDuration() {
 	// "DTEND" nor "DURATION" property, the event's duration is taken to
 	// be one day.
 
-	if dtstart, ok := evt.Property("DTSTART"); !ok ||
-		!(len(dtstart.Params["VALUE"]) == 0 ||
-			dtstart.Params.Contains("VALUE", "DATE")) {
+	if dtstart, ok := evt.Property("DTSTART"); !ok || !isDateValue(dtstart) {
 		return
 	}
 
@@ -133,7 +811,8 @@ func (evt *Event) applyImplicitEndOfDayDuration() {
 	// "DTEND" property, the event ends on the same calendar date and
 	// time of day specified by the "DTSTART" property.
 
-	if dtstart, ok := evt.Property("DTSTART"); !ok || !dtstart.Params.Contains("VALUE", "DATE-TIME") {
+	dtstart, ok := evt.Property("DTSTART")
+	if !ok || !isTimeValue(dtstart) || isDateValue(dtstart) {
 		return
 	}
 
@@ -141,6 +820,17 @@ func (evt *Event) applyImplicitEndOfDayDuration() {
 		return
 	}
 
+	if _, ok := evt.Property("DURATION"); ok {
+		return
+	}
+
+	// only an explicit VALUE=DATE-TIME ends at the end of the day; other
+	// DATE-TIME values end at the start of the event
+	if !dtstart.Params.Contains("VALUE", "DATE-TIME") {
+		evt.End = evt.Start
+		return
+	}
+
 	evt.End = time.Date(
 		evt.Start.Year(),
 		evt.Start.Month(),
@@ -0,0 +1,526 @@
+package parse_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bounoable/ical/internal/testutil"
+	"github.com/bounoable/ical/lex"
+	"github.com/bounoable/ical/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalendar_Span(t *testing.T) {
+	t.Run("empty calendar", func(t *testing.T) {
+		earliest, latest, ok := parse.Calendar{}.Span()
+		assert.False(t, ok)
+		assert.True(t, earliest.IsZero())
+		assert.True(t, latest.IsZero())
+	})
+
+	t.Run("all-day & timed events", func(t *testing.T) {
+		input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+DTSTART;VALUE=DATE-TIME:20200105T100000Z
+DTEND;VALUE=DATE-TIME:20200105T113000Z
+END:VEVENT
+BEGIN:VEVENT
+DTSTART;VALUE=DATE:20200110
+END:VEVENT
+BEGIN:VEVENT
+DTSTART;VALUE=DATE-TIME:20200102T080000Z
+DTEND;VALUE=DATE-TIME:20200102T090000Z
+END:VEVENT
+END:VCALENDAR`
+
+		cal, err := parse.Items(lex.Text(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		earliest, latest, ok := cal.Span()
+		assert.True(t, ok)
+		assert.Equal(t, time.Date(2020, time.January, 2, 8, 0, 0, 0, time.UTC), earliest)
+		// the all-day event ends (exclusively) at midnight of the following day
+		assert.Equal(t, time.Date(2020, time.January, 11, 0, 0, 0, 0, time.Local), latest)
+	})
+}
+
+func TestParameters_First(t *testing.T) {
+	params := parse.Parameters{
+		"X-FILENAME": []string{`"my file.txt"`, "other.txt"},
+		"X-EMPTY":    nil,
+		"FMTTYPE":    []string{"text/plain"},
+	}
+
+	val, ok := params.First("X-FILENAME")
+	assert.True(t, ok)
+	assert.Equal(t, "my file.txt", val)
+
+	val, ok = params.First("FMTTYPE")
+	assert.True(t, ok)
+	assert.Equal(t, "text/plain", val)
+
+	_, ok = params.First("X-EMPTY")
+	assert.False(t, ok)
+
+	_, ok = params.First("X-MISSING")
+	assert.False(t, ok)
+}
+
+func TestParameters_Values(t *testing.T) {
+	params := parse.Parameters{
+		"SCHEDULE-STATUS": []string{`"3.7"`, `"5.3"`},
+		"ROLE":            []string{"CHAIR"},
+	}
+
+	assert.Equal(t, []string{"3.7", "5.3"}, params.Values("SCHEDULE-STATUS"))
+	assert.Equal(t, []string{"CHAIR"}, params.Values("ROLE"))
+	assert.Nil(t, params.Values("X-MISSING"))
+}
+
+func TestProperty_ValueType(t *testing.T) {
+	tests := []struct {
+		prop     parse.Property
+		expected string
+	}{
+		{
+			prop:     parse.Property{Name: "DTSTART"},
+			expected: "DATE-TIME",
+		},
+		{
+			prop:     parse.Property{Name: "DTSTART", Params: parse.Parameters{"VALUE": {"DATE"}}},
+			expected: "DATE",
+		},
+		{
+			prop:     parse.Property{Name: "DTEND", Params: parse.Parameters{"VALUE": {"date"}}},
+			expected: "DATE",
+		},
+		{
+			prop:     parse.Property{Name: "ATTENDEE"},
+			expected: "CAL-ADDRESS",
+		},
+		{
+			prop:     parse.Property{Name: "SUMMARY"},
+			expected: "TEXT",
+		},
+		{
+			prop:     parse.Property{Name: "X-DATA", Params: parse.Parameters{"VALUE": {"XML"}}},
+			expected: "XML",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.prop.Name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.prop.ValueType())
+		})
+	}
+}
+
+func TestEvent_SetPartStat(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+ORGANIZER:mailto:boss@example.com
+ATTENDEE;PARTSTAT=NEEDS-ACTION;RSVP=TRUE:mailto:jdoe@example.com
+ATTENDEE;CN=Jane Smith:MAILTO:jsmith@example.com
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	evt := cal.Events[0]
+
+	assert.NoError(t, evt.SetPartStat("jdoe@example.com", "ACCEPTED"))
+	assert.NoError(t, evt.SetPartStat("JSmith@example.com", "DECLINED"))
+	assert.Error(t, evt.SetPartStat("boss@example.com", "ACCEPTED"))
+
+	// the event in the calendar must not change through the copy
+	assert.Equal(t, parse.Parameters{
+		"PARTSTAT": {"NEEDS-ACTION"},
+		"RSVP":     {"TRUE"},
+	}, cal.Events[0].Properties[1].Params)
+	assert.Equal(t, "NEEDS-ACTION", cal.Events[0].Attendees[0].PartStat)
+	assert.Equal(t, "ACCEPTED", evt.Attendees[0].PartStat)
+
+	assert.Equal(t, parse.Parameters{
+		"PARTSTAT": {"ACCEPTED"},
+		"RSVP":     {"TRUE"},
+	}, evt.Properties[1].Params)
+	assert.Equal(t, parse.Parameters{
+		"CN":       {"Jane Smith"},
+		"PARTSTAT": {"DECLINED"},
+	}, evt.Properties[2].Params)
+
+	evt = parse.Event{Properties: []parse.Property{{Name: "ATTENDEE", Value: "mailto:jdoe@example.com"}}}
+	assert.NoError(t, evt.SetPartStat("jdoe@example.com", "TENTATIVE"))
+	assert.Equal(t, parse.Parameters{"PARTSTAT": {"TENTATIVE"}}, evt.Properties[0].Params)
+}
+
+func TestCalendar_EventsWithCategory(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:1
+CATEGORIES:WORK,MEETING
+END:VEVENT
+BEGIN:VEVENT
+UID:2
+CATEGORIES:PERSONAL
+END:VEVENT
+BEGIN:VEVENT
+UID:3
+CATEGORIES:Meeting
+CATEGORIES:Work
+END:VEVENT
+BEGIN:VEVENT
+UID:4
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uids := func(events []parse.Event) []string {
+		var uids []string
+		for _, evt := range events {
+			uids = append(uids, evt.UID)
+		}
+		return uids
+	}
+
+	assert.Equal(t, []string{"1", "3"}, uids(cal.EventsWithCategory("WORK")))
+	assert.Equal(t, []string{"1", "3"}, uids(cal.EventsWithCategory("meeting")))
+	assert.Equal(t, []string{"2"}, uids(cal.EventsWithCategory("Personal")))
+	assert.Empty(t, cal.EventsWithCategory("HOLIDAY"))
+}
+
+func TestCalendar_IsEmpty(t *testing.T) {
+	assert.True(t, parse.Calendar{}.IsEmpty())
+	assert.True(t, parse.Calendar{Events: []parse.Event{}}.IsEmpty())
+	assert.False(t, parse.Calendar{Events: []parse.Event{{}}}.IsEmpty())
+	assert.False(t, parse.Calendar{Components: []parse.Component{{Name: "VTIMEZONE"}}}.IsEmpty())
+}
+
+func TestCalendar_VersionRange(t *testing.T) {
+	tests := map[string][2]string{
+		"2.0":     {"2.0", "2.0"},
+		"1.0;2.0": {"1.0", "2.0"},
+		"2.0;2.0": {"2.0", "2.0"},
+		"":        {"", ""},
+	}
+
+	for version, expected := range tests {
+		t.Run(version, func(t *testing.T) {
+			cal, err := parse.Items(lex.Text("BEGIN:VCALENDAR\nVERSION:" + version + "\nEND:VCALENDAR"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			min, max := cal.VersionRange()
+			assert.Equal(t, expected, [2]string{min, max})
+		})
+	}
+}
+
+func TestCalendar_HeaderProperty(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+PRODID:-//Example//Product//EN
+X-WR-CALNAME:Work
+x-wr-calname:Work (old)
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prop, ok := cal.HeaderProperty("prodid")
+	assert.True(t, ok)
+	assert.Equal(t, "-//Example//Product//EN", prop.Value)
+
+	prop, ok = cal.HeaderProperty("X-WR-CALNAME")
+	assert.True(t, ok)
+	assert.Equal(t, "Work", prop.Value)
+
+	_, ok = cal.HeaderProperty("METHOD")
+	assert.False(t, ok)
+
+	props := cal.HeaderProperties("X-WR-CALNAME")
+	assert.Len(t, props, 2)
+	assert.Equal(t, "Work", props[0].Value)
+	assert.Equal(t, "Work (old)", props[1].Value)
+	assert.Empty(t, cal.HeaderProperties("METHOD"))
+}
+
+func TestCalendar_RelatedCalendarID(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+X-WR-CALNAME:Work
+X-WR-RELCALID:7a2c1b94-0f3e-4d55-9a1c-3c8e2f6b7d10
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "7a2c1b94-0f3e-4d55-9a1c-3c8e2f6b7d10", cal.RelatedCalendarID)
+	assert.Equal(t, []parse.Property{
+		testutil.Property("X-WR-CALNAME", "Work", nil),
+	}, cal.UnknownProperties())
+
+	prop, ok := cal.HeaderProperty("X-WR-CALNAME")
+	assert.True(t, ok)
+	assert.Equal(t, "Work", prop.Value)
+}
+
+func TestEvent_SetStart(t *testing.T) {
+	berlin := testutil.LoadLocation("Europe/Berlin")
+
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:1
+DTSTART;X-FOO=bar:20200101T100000Z
+DURATION:PT1H
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	evt := cal.Events[0]
+
+	evt.SetStart(time.Date(2020, time.January, 2, 9, 30, 0, 0, berlin), false)
+	assert.Equal(t, time.Date(2020, time.January, 2, 9, 30, 0, 0, berlin), evt.Start)
+	assert.Equal(t, testutil.Property("DTSTART", "20200102T093000", parse.Parameters{
+		"TZID":  {"Europe/Berlin"},
+		"X-FOO": {"bar"},
+	}), evt.Properties[1])
+
+	evt.SetStart(time.Date(2020, time.January, 3, 9, 30, 0, 0, time.UTC), true)
+	assert.Equal(t, time.Date(2020, time.January, 3, 0, 0, 0, 0, time.UTC), evt.Start)
+	assert.Equal(t, testutil.Property("DTSTART", "20200103", parse.Parameters{
+		"VALUE": {"DATE"},
+		"X-FOO": {"bar"},
+	}), evt.Properties[1])
+
+	evt.SetEnd(time.Date(2020, time.January, 5, 0, 0, 0, 0, time.UTC), true)
+	assert.Equal(t, time.Date(2020, time.January, 5, 0, 0, 0, 0, time.UTC), evt.End)
+	assert.Equal(t, []parse.Property{
+		testutil.Property("UID", "1", nil),
+		testutil.Property("DTSTART", "20200103", parse.Parameters{"VALUE": {"DATE"}, "X-FOO": {"bar"}}),
+		testutil.Property("DTEND", "20200105", parse.Parameters{"VALUE": {"DATE"}}),
+	}, evt.Properties)
+
+	evt.SetEnd(time.Date(2020, time.January, 5, 12, 0, 0, 0, time.UTC), false)
+	assert.Equal(t, testutil.Property("DTEND", "20200105T120000Z", nil), evt.Properties[2])
+
+	// the event in the calendar must not change through the copy
+	assert.Equal(t, time.Date(2020, time.January, 1, 10, 0, 0, 0, time.UTC), cal.Events[0].Start)
+	assert.Equal(t, []parse.Property{
+		testutil.Property("UID", "1", nil),
+		testutil.Property("DTSTART", "20200101T100000Z", parse.Parameters{"X-FOO": {"bar"}}),
+		testutil.Property("DURATION", "PT1H", nil),
+	}, cal.Events[0].Properties)
+}
+
+func TestCalendar_UnknownProperties(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Example//Product//EN
+X-WR-CALNAME:Work
+BEGIN:VEVENT
+UID:1
+DTSTART:20200101T100000Z
+DURATION:PT1H
+SUMMARY:Meeting
+X-MICROSOFT-CDO-BUSYSTATUS:BUSY
+COLOR:turquoise
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER:-PT15M
+X-APPLE-DEFAULT-ALARM:TRUE
+END:VALARM
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []parse.Property{
+		testutil.Property("X-WR-CALNAME", "Work", nil),
+		testutil.Property("X-MICROSOFT-CDO-BUSYSTATUS", "BUSY", nil),
+		testutil.Property("COLOR", "turquoise", nil),
+		testutil.Property("X-APPLE-DEFAULT-ALARM", "TRUE", nil),
+	}, cal.UnknownProperties())
+}
+
+func TestEvent_ComputedDuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		opts     []parse.Option
+		expected time.Duration
+	}{
+		{
+			name:     "all-day",
+			body:     "DTSTART;VALUE=DATE:20200101\nDTEND;VALUE=DATE:20200104",
+			expected: 72 * time.Hour,
+		},
+		{
+			name:     "all-day inclusive end",
+			body:     "DTSTART;VALUE=DATE:20200101\nDTEND;VALUE=DATE:20200103",
+			opts:     []parse.Option{parse.InclusiveEnds},
+			expected: 72 * time.Hour,
+		},
+		{
+			name:     "all-day over DST transition",
+			body:     "DTSTART;VALUE=DATE:20200328\nDTEND;VALUE=DATE:20200330",
+			opts:     []parse.Option{parse.Location(testutil.LoadLocation("Europe/Berlin"))},
+			expected: 48 * time.Hour,
+		},
+		{
+			name:     "implicit all-day",
+			body:     "DTSTART;VALUE=DATE:20200101",
+			expected: 24 * time.Hour,
+		},
+		{
+			name:     "dtend",
+			body:     "DTSTART:20200101T100000Z\nDTEND:20200101T113000Z",
+			expected: 90 * time.Minute,
+		},
+		{
+			name:     "duration",
+			body:     "DTSTART:20200101T100000Z\nDURATION:PT45M",
+			expected: 45 * time.Minute,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := "BEGIN:VCALENDAR\nBEGIN:VEVENT\n" + test.body + "\nEND:VEVENT\nEND:VCALENDAR"
+			cal, err := parse.Items(lex.Text(input), test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, test.expected, cal.Events[0].ComputedDuration())
+		})
+	}
+
+	assert.Zero(t, parse.Event{}.ComputedDuration())
+}
+
+func TestAlarm_TriggerTime(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+DTSTART:20200101T100000Z
+DTEND:20200101T120000Z
+BEGIN:VALARM
+TRIGGER:-PT1H
+END:VALARM
+BEGIN:VALARM
+TRIGGER;RELATED=END:-PT15M
+END:VALARM
+BEGIN:VALARM
+TRIGGER;VALUE=DATE-TIME:20191231T080000Z
+END:VALARM
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evt := cal.Events[0]
+	assert.False(t, evt.Alarms[0].Trigger.IsAbsolute())
+	assert.Equal(t, time.Date(2020, time.January, 1, 9, 0, 0, 0, time.UTC), evt.Alarms[0].TriggerTime(evt))
+	assert.Equal(t, time.Date(2020, time.January, 1, 11, 45, 0, 0, time.UTC), evt.Alarms[1].TriggerTime(evt))
+	assert.True(t, evt.Alarms[2].Trigger.IsAbsolute())
+	assert.Equal(t, time.Date(2019, time.December, 31, 8, 0, 0, 0, time.UTC), evt.Alarms[2].TriggerTime(evt))
+}
+
+func TestAlarm_FireTimes(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+DTSTART:20200101T100000Z
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER:-PT15M
+REPEAT:2
+DURATION:PT5M
+END:VALARM
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER:-PT1H
+END:VALARM
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evt := cal.Events[0]
+	assert.Equal(t, []time.Time{
+		time.Date(2020, time.January, 1, 9, 45, 0, 0, time.UTC),
+		time.Date(2020, time.January, 1, 9, 50, 0, 0, time.UTC),
+		time.Date(2020, time.January, 1, 9, 55, 0, 0, time.UTC),
+	}, evt.Alarms[0].FireTimes(evt))
+	assert.Equal(t, []time.Time{
+		time.Date(2020, time.January, 1, 9, 0, 0, 0, time.UTC),
+	}, evt.Alarms[1].FireTimes(evt))
+}
+
+func TestEvent_Days(t *testing.T) {
+	parseEvent := func(body string) parse.Event {
+		cal, err := parse.Items(lex.Text("BEGIN:VCALENDAR\nBEGIN:VEVENT\n" + body + "\nEND:VEVENT\nEND:VCALENDAR"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cal.Events[0]
+	}
+
+	date := func(month time.Month, day int) time.Time { return time.Date(2020, month, day, 0, 0, 0, 0, time.Local) }
+
+	assert.Equal(t, []time.Time{
+		date(time.February, 28),
+		date(time.February, 29),
+		date(time.March, 1),
+	}, parseEvent("DTSTART;VALUE=DATE:20200228\nDTEND;VALUE=DATE:20200302").Days())
+
+	assert.Equal(t, []time.Time{date(time.January, 1)}, parseEvent("DTSTART;VALUE=DATE:20200101").Days())
+	assert.Nil(t, parseEvent("DTSTART:20200101T100000Z\nDTEND:20200103T100000Z").Days())
+}
+
+func TestCalendar_AddEvent(t *testing.T) {
+	cal, err := parse.Items(lex.Text("BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:1\nEND:VEVENT\nEND:VCALENDAR"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cal.AddEvent(parse.NewEvent(map[string]string{"UID": "2", "DTSTART": "20200101T100000Z"}))
+	cal.AddEvent(parse.NewEvent(map[string]string{"UID": "2", "RECURRENCE-ID": "20200101T100000Z"}))
+	cal.AddEvent(parse.Event{Properties: []parse.Property{testutil.Property("UID", "3", nil)}})
+	assert.Len(t, cal.Events, 4)
+
+	snapshot := cal
+	assert.True(t, cal.RemoveEventByUID("2"))
+	if assert.Len(t, cal.Events, 2) {
+		assert.Equal(t, "1", cal.Events[0].UID)
+	}
+	if assert.Len(t, snapshot.Events, 4) {
+		assert.Equal(t, "2", snapshot.Events[1].UID)
+		assert.Equal(t, "2", snapshot.Events[2].UID)
+	}
+
+	assert.True(t, cal.RemoveEventByUID("3"))
+	assert.False(t, cal.RemoveEventByUID("2"))
+	assert.Len(t, cal.Events, 1)
+}
@@ -3,23 +3,27 @@ package parse
 import (
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"time"
-	"unicode"
 	"unicode/utf8"
 )
 
-func parseDuration(raw string) (time.Duration, error) {
+// parseDuration parses a DURATION value. If lenient is true, a week duration
+// may be combined with days and time (e.g. "P1WT1H"), which is invalid but
+// emitted by some producers. The combined durations are summed.
+func parseDuration(raw string, lenient bool) (time.Duration, error) {
 	if len(raw) == 0 {
 		return 0, nil
 	}
-	return (&durationParser{value: raw}).parse()
+	return (&durationParser{value: raw, lenient: lenient}).parse()
 }
 
 type durationParser struct {
-	value string
-	pos   int
-	width int
+	value   string
+	pos     int
+	width   int
+	lenient bool
 }
 
 const day = time.Hour * 24
@@ -47,11 +51,23 @@ func (p *durationParser) parse() (time.Duration, error) {
 		p.backup()
 	}
 
-	if r, err = p.next(); r != 'P' {
-		return 0, fmt.Errorf("expected 'P' at pos %d; got %s", p.pos, string(r))
+	if r, err = p.next(); err != nil || r != 'P' {
+		return 0, fmt.Errorf("expected 'P' at pos %d; got %s", p.pos, describeRune(r, err))
 	}
 
-	if r, err = p.next(); err != nil {
+	dur, err := p.parseValue(true)
+	if err != nil {
+		return 0, err
+	}
+
+	return dur * multiplier, nil
+}
+
+// parseValue parses the value after the "P". If allowWeek is false, a
+// dur-week is not allowed.
+func (p *durationParser) parseValue(allowWeek bool) (time.Duration, error) {
+	r, err := p.next()
+	if err != nil {
 		return 0, p.unexpectedEnd()
 	}
 
@@ -60,7 +76,7 @@ func (p *durationParser) parse() (time.Duration, error) {
 		if err != nil {
 			return 0, fmt.Errorf("failed to parse time duration: %w", err)
 		}
-		return dur * multiplier, nil
+		return dur, nil
 	}
 	p.backup()
 
@@ -73,14 +89,38 @@ func (p *durationParser) parse() (time.Duration, error) {
 		return 0, p.unexpectedEnd()
 	}
 
-	switch r {
-	case 'W':
-		return week * time.Duration(num) * multiplier, nil
-	case 'D':
-		dayDur := day * time.Duration(num)
+	switch {
+	case r == 'W' && allowWeek:
+		weekDur, ok := multiply(week, num)
+		if !ok {
+			return 0, p.outOfRange()
+		}
 
 		if r, err = p.next(); err != nil {
-			return dayDur * multiplier, nil
+			return weekDur, nil
+		}
+
+		if !p.lenient {
+			return 0, fmt.Errorf("unexpected %s at pos %d: a week duration cannot be combined with days or time", string(r), p.pos)
+		}
+		p.backup()
+
+		dur, err := p.parseValue(false)
+		if err != nil {
+			return 0, err
+		}
+		if dur > maxDuration-weekDur {
+			return 0, p.outOfRange()
+		}
+		return weekDur + dur, nil
+	case r == 'D':
+		dayDur, ok := multiply(day, num)
+		if !ok {
+			return 0, p.outOfRange()
+		}
+
+		if r, err = p.next(); err != nil {
+			return dayDur, nil
 		}
 
 		if r == 'T' {
@@ -88,12 +128,17 @@ func (p *durationParser) parse() (time.Duration, error) {
 			if err != nil {
 				return 0, err
 			}
-			return (dayDur + timeDur) * multiplier, nil
+			if timeDur > maxDuration-dayDur {
+				return 0, p.outOfRange()
+			}
+			return dayDur + timeDur, nil
 		}
 
 		return 0, fmt.Errorf("unexpected %s at pos %d", string(r), p.pos)
-	default:
+	case allowWeek:
 		return 0, fmt.Errorf("expected one of [W D] at pos %d; got %s", p.pos, string(r))
+	default:
+		return 0, fmt.Errorf("expected D at pos %d; got %s", p.pos, string(r))
 	}
 }
 
@@ -124,7 +169,11 @@ func (p *durationParser) parseTime() (time.Duration, error) {
 			return 0, fmt.Errorf("expected one of [H M S] at pos %d; got %s", p.pos, string(r))
 		}
 
-		total += one * time.Duration(num)
+		dur, ok := multiply(one, num)
+		if !ok || dur > maxDuration-total {
+			return 0, p.outOfRange()
+		}
+		total += dur
 
 		if r, err = p.next(); err != nil {
 			return total, nil
@@ -133,31 +182,56 @@ func (p *durationParser) parseTime() (time.Duration, error) {
 	}
 }
 
-func (p *durationParser) parseDigits() (int, error) {
-	var r rune
-	var err error
-	var digits string
+func (p *durationParser) parseDigits() (int64, error) {
+	start := p.pos
 
-	if r, err = p.next(); err != nil {
+	r, err := p.next()
+	if err != nil {
 		return 0, p.unexpectedEnd()
 	}
 
-	for unicode.IsDigit(r) {
-		digits += string(r)
+	if !isDigit(r) {
+		return 0, fmt.Errorf("expected digit at pos %d; got %s", p.pos, string(r))
+	}
+
+	for isDigit(r) {
 		if r, err = p.next(); err != nil {
 			return 0, p.unexpectedEnd()
 		}
 	}
 	p.backup()
 
-	num, err := strconv.Atoi(digits)
+	digits := p.value[start:p.pos]
+	num, err := strconv.ParseInt(digits, 10, 64)
 	if err != nil {
-		return 0, fmt.Errorf("string to int conversion failed: %w", err)
+		return 0, fmt.Errorf("number %q at pos %d is out of range", digits, start)
 	}
 
 	return num, nil
 }
 
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+const maxDuration = time.Duration(math.MaxInt64)
+
+// multiply returns unit * n or false if the product overflows a time.Duration.
+func multiply(unit time.Duration, n int64) (time.Duration, bool) {
+	if n > int64(maxDuration/unit) {
+		return 0, false
+	}
+	return unit * time.Duration(n), true
+}
+
+// describeRune describes the rune r that has been read with err for error messages.
+func describeRune(r rune, err error) string {
+	if err != nil {
+		return "end of duration"
+	}
+	return string(r)
+}
+
 var errEndOfDuration = errors.New("end of duration")
 
 func (p *durationParser) next() (rune, error) {
@@ -178,3 +252,7 @@ func (p *durationParser) backup() {
 func (p *durationParser) unexpectedEnd() error {
 	return fmt.Errorf("unexpected end of duration at pos %d", p.pos)
 }
+
+func (p *durationParser) outOfRange() error {
+	return fmt.Errorf("duration at pos %d is out of range", p.pos)
+}
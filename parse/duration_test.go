@@ -32,10 +32,69 @@ func TestParseDuration(t *testing.T) {
 
 func testParseDuration(raw string, expected time.Duration) func(*testing.T) {
 	return func(t *testing.T) {
-		dur, err := parseDuration(raw)
+		dur, err := parseDuration(raw, false)
 		if err != nil {
 			t.Fatal(err)
 		}
 		assert.Equal(t, expected, dur)
 	}
 }
+
+func TestParseDuration_weekCombined(t *testing.T) {
+	_, err := parseDuration("P1WT1H", false)
+	assert.EqualError(t, err, "unexpected T at pos 4: a week duration cannot be combined with days or time")
+
+	tests := map[string]time.Duration{
+		"P1WT1H":    week + time.Hour,
+		"P2W3D":     2*week + 3*day,
+		"-P1W1DT1M": -(week + day + time.Minute),
+	}
+
+	for raw, expected := range tests {
+		t.Run(raw, func(t *testing.T) {
+			dur, err := parseDuration(raw, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, expected, dur)
+		})
+	}
+
+	_, err = parseDuration("P1W2W", true)
+	assert.Error(t, err)
+}
+
+func TestParseDuration_malformed(t *testing.T) {
+	tests := map[string]string{
+		"P":                      "unexpected end of duration at pos 1",
+		"PT":                     "failed to parse time duration: failed to parse digits: unexpected end of duration at pos 2",
+		"P-":                     "failed to parse digits: expected digit at pos 2; got -",
+		"PTX":                    "failed to parse time duration: failed to parse digits: expected digit at pos 3; got X",
+		"+":                      "expected 'P' at pos 1; got end of duration",
+		"X":                      "expected 'P' at pos 1; got X",
+		"P1":                     "failed to parse digits: unexpected end of duration at pos 2",
+		"PT1":                    "failed to parse time duration: failed to parse digits: unexpected end of duration at pos 3",
+		"P1DT":                   "failed to parse digits: unexpected end of duration at pos 4",
+		"P99999999999999999999W": `failed to parse digits: number "99999999999999999999" at pos 1 is out of range`,
+		"P9999999999W":           "duration at pos 12 is out of range",
+		"PT9999999999999H":       "failed to parse time duration: duration at pos 16 is out of range",
+	}
+
+	for raw, expected := range tests {
+		t.Run(raw, func(t *testing.T) {
+			_, err := parseDuration(raw, false)
+			assert.EqualError(t, err, expected)
+		})
+	}
+}
+
+func FuzzParseDuration(f *testing.F) {
+	for _, seed := range []string{"PT10S", "-P7DT4H10S", "+P4W", "P1WT1H", "P", "PT", "P-", "PTX", "P1D1", "PT1H1"} {
+		f.Add(seed, false)
+		f.Add(seed, true)
+	}
+
+	f.Fuzz(func(t *testing.T, raw string, lenient bool) {
+		parseDuration(raw, lenient)
+	})
+}
@@ -0,0 +1,285 @@
+package parse
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Expand returns a copy of the calendar in which every recurring event is
+// replaced by a single event for each of its occurrences that begin within
+// [from, to). The events of the occurrences have their RECURRENCE-ID set and
+// don't have any RRULE, RDATE, EXRULE or EXDATE properties. Occurrences that
+// are overridden by an event with the same UID and a matching RECURRENCE-ID
+// are replaced by the overriding event. An overriding event with
+// RANGE=THISANDFUTURE also overrides all following occurrences, which are
+// shifted by the same offset as the overriding event. Non-recurring events
+// are kept as is.
+func (cal Calendar) Expand(from, to time.Time) Calendar {
+	overrides, masters := cal.overrides()
+
+	expanded := cal
+	expanded.Events = make([]Event, 0, len(cal.Events))
+	for _, evt := range cal.Events {
+		switch {
+		case !evt.RecurrenceID.IsZero() && masters[evt.UID]:
+			// added together with the occurrences of the master event
+		case evt.isRecurring():
+			expanded.Events = append(expanded.Events, evt.expand(from, to, overrides[evt.UID])...)
+		default:
+			expanded.Events = append(expanded.Events, evt)
+		}
+	}
+
+	return expanded
+}
+
+// Upcoming returns the first n events that start at or after now, sorted by
+// their Start, e.g. for a dashboard. Recurring events are expanded (see Expand),
+// so a recurring event contributes an event for each of its upcoming occurrences.
+func (cal Calendar) Upcoming(now time.Time, n int) []Event {
+	if n <= 0 {
+		return nil
+	}
+
+	overrides, _ := cal.overrides()
+
+	var events []Event
+	for _, evt := range cal.Events {
+		if evt.isRecurring() {
+			events = append(events, evt.upcoming(now, n, overrides[evt.UID])...)
+			continue
+		}
+		// overrides of recurring events are upcoming events on their own
+		if !evt.Start.Before(now) {
+			events = append(events, evt)
+		}
+	}
+
+	sort.SliceStable(events, func(a, b int) bool { return events[a].Start.Before(events[b].Start) })
+	if len(events) > n {
+		events = events[:n]
+	}
+
+	return events
+}
+
+// overrides returns the events that override an occurrence of a recurring
+// event by their UID and the UIDs of the recurring events.
+func (cal Calendar) overrides() (map[string][]Event, map[string]bool) {
+	overrides := make(map[string][]Event)
+	masters := make(map[string]bool)
+	for _, evt := range cal.Events {
+		if !evt.RecurrenceID.IsZero() {
+			overrides[evt.UID] = append(overrides[evt.UID], evt)
+		} else if evt.isRecurring() {
+			masters[evt.UID] = true
+		}
+	}
+	return overrides, masters
+}
+
+func (evt Event) isRecurring() bool {
+	return evt.RecurrenceID.IsZero() && (len(evt.RecurrenceRules) > 0 || len(evt.RDates) > 0)
+}
+
+// expand returns the events of the occurrences of evt within [from, to).
+func (evt Event) expand(from, to time.Time, overrides []Event) []Event {
+	var events []Event
+	for _, o := range overrides {
+		if !o.Start.Before(from) && o.Start.Before(to) {
+			events = append(events, o)
+		}
+	}
+
+	set := newOverrideSet(overrides)
+	for _, t := range evt.Occurrences(from, to) {
+		if occ, ok := set.occurrence(evt, t); ok {
+			events = append(events, occ)
+		}
+	}
+
+	sort.SliceStable(events, func(a, b int) bool { return events[a].Start.Before(events[b].Start) })
+
+	return events
+}
+
+// upcoming returns the events of the first n occurrences of evt that start at
+// or after now and are not overridden. The occurrences are walked lazily, so
+// that occurrences that are far in the future are found without expanding
+// large ranges.
+func (evt Event) upcoming(now time.Time, n int, overrides []Event) []Event {
+	var events []Event
+	set := newOverrideSet(overrides)
+	it := evt.recurrenceSet()
+	for len(events) < n {
+		t, ok := it.next()
+		if !ok {
+			break
+		}
+
+		// only the upcoming occurrences are built, because building the
+		// events of the past occurrences of e.g. minutely rules is expensive
+		if start, ok := set.start(t); !ok || start.Before(now) {
+			continue
+		}
+
+		occ, _ := set.occurrence(evt, t)
+		events = append(events, occ)
+	}
+	return events
+}
+
+// overrideSet are the overrides of the occurrences of a recurring event.
+type overrideSet struct {
+	overridden map[int64]bool
+	// future are the THISANDFUTURE overrides, sorted by their RecurrenceID
+	future []Event
+}
+
+func newOverrideSet(overrides []Event) overrideSet {
+	set := overrideSet{overridden: make(map[int64]bool)}
+	for _, o := range overrides {
+		set.overridden[o.RecurrenceID.Unix()] = true
+		if o.RecurrenceRange == "THISANDFUTURE" {
+			set.future = append(set.future, o)
+		}
+	}
+	sort.Slice(set.future, func(a, b int) bool { return set.future[a].RecurrenceID.Before(set.future[b].RecurrenceID) })
+	return set
+}
+
+// occurrence returns the event of the occurrence of master at t. ok is false
+// if the occurrence is overridden by an override with a matching RECURRENCE-ID.
+func (set overrideSet) occurrence(master Event, t time.Time) (Event, bool) {
+	future, ok := set.override(t)
+	if !ok {
+		return Event{}, false
+	}
+
+	if future != nil {
+		return future.futureOccurrence(t), true
+	}
+
+	return master.occurrence(t), true
+}
+
+// start returns the start of the occurrence at t without building its event,
+// i.e. t shifted by the THISANDFUTURE override that applies to t. ok is false
+// if the occurrence is overridden by an override with a matching RECURRENCE-ID.
+func (set overrideSet) start(t time.Time) (time.Time, bool) {
+	future, ok := set.override(t)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	if future != nil {
+		return future.Start.Add(t.Sub(future.RecurrenceID)), true
+	}
+
+	return t, true
+}
+
+// override returns the THISANDFUTURE override that applies to the occurrence
+// at t, or nil if none applies. ok is false if the occurrence is overridden by
+// an override with a matching RECURRENCE-ID.
+func (set overrideSet) override(t time.Time) (future *Event, ok bool) {
+	if set.overridden[t.Unix()] {
+		return nil, false
+	}
+
+	// the latest THISANDFUTURE override before t applies to t
+	i := sort.Search(len(set.future), func(i int) bool { return !set.future[i].RecurrenceID.Before(t) }) - 1
+	if i >= 0 {
+		return &set.future[i], true
+	}
+
+	return nil, true
+}
+
+// occurrence returns the event of the occurrence of evt that starts at t.
+func (evt Event) occurrence(t time.Time) Event {
+	occ := evt
+	occ.Recurrence = nil
+	occ.RecurrenceRules = nil
+	occ.ExceptionRules = nil
+	occ.RDates = nil
+	occ.ExDates = nil
+	occ.RecurrenceID = t
+	occ.Start = t
+	if !evt.End.IsZero() {
+		// the end may be in another timezone than the start, e.g. for flights
+		occ.End = t.Add(evt.End.Sub(evt.Start)).In(evt.End.Location())
+	}
+	occ.Alarms = append([]Alarm(nil), evt.Alarms...)
+
+	occ.Properties = make([]Property, 0, len(evt.Properties)+1)
+	for _, prop := range evt.Properties {
+		prop.Params = prop.Params.clone()
+
+		switch prop.Name {
+		case "RRULE", "RDATE", "EXRULE", "EXDATE":
+			continue
+		case "DTSTART":
+			prop.Value = formatTime(occ.Start, prop)
+			occ.Properties = append(occ.Properties, Property{
+				Name:   "RECURRENCE-ID",
+				Params: prop.Params.clone(),
+				Value:  prop.Value,
+			})
+		case "DTEND":
+			prop.Value = formatTime(occ.End, prop)
+		}
+
+		occ.Properties = append(occ.Properties, prop)
+	}
+
+	return occ
+}
+
+// futureOccurrence returns the event of the occurrence at t that is overridden
+// by the THISANDFUTURE override evt. The occurrence is shifted by the offset
+// between the RecurrenceID and the Start of evt.
+func (evt Event) futureOccurrence(t time.Time) Event {
+	offset := t.Sub(evt.RecurrenceID)
+
+	occ := evt
+	occ.RecurrenceID = t
+	occ.RecurrenceRange = ""
+	occ.Start = evt.Start.Add(offset)
+	if !evt.End.IsZero() {
+		occ.End = evt.End.Add(offset)
+	}
+	occ.Alarms = append([]Alarm(nil), evt.Alarms...)
+
+	occ.Properties = make([]Property, len(evt.Properties))
+	for i, prop := range evt.Properties {
+		prop.Params = prop.Params.clone()
+
+		switch prop.Name {
+		case "RECURRENCE-ID":
+			delete(prop.Params, "RANGE")
+			prop.Value = formatTime(occ.RecurrenceID, prop)
+		case "DTSTART":
+			prop.Value = formatTime(occ.Start, prop)
+		case "DTEND":
+			prop.Value = formatTime(occ.End, prop)
+		}
+
+		occ.Properties[i] = prop
+	}
+
+	return occ
+}
+
+// formatTime formats t in the format of the date / datetime value of prop.
+func formatTime(t time.Time, prop Property) string {
+	switch {
+	case isDateValue(prop):
+		return t.Format(layoutDate)
+	case strings.HasSuffix(prop.Value, "Z"):
+		return t.UTC().Format(layoutDateTimeUTC)
+	default:
+		return t.Format(layoutDateTimeLocal)
+	}
+}
@@ -0,0 +1,338 @@
+package parse_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bounoable/ical/internal/testutil"
+	"github.com/bounoable/ical/lex"
+	"github.com/bounoable/ical/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalendar_Expand(t *testing.T) {
+	berlin := testutil.LoadLocation("Europe/Berlin")
+
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:weekly
+SUMMARY:Weekly
+DTSTART;TZID=Europe/Berlin:20200106T100000
+DTEND;TZID=Europe/Berlin:20200106T110000
+RRULE:FREQ=WEEKLY;COUNT=5
+EXDATE;TZID=Europe/Berlin:20200113T100000
+END:VEVENT
+BEGIN:VEVENT
+UID:single
+SUMMARY:Single
+DTSTART:20200301T100000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:weekly
+SUMMARY:Moved
+RECURRENCE-ID;TZID=Europe/Berlin:20200120T100000
+DTSTART;TZID=Europe/Berlin:20200121T140000
+DTEND;TZID=Europe/Berlin:20200121T150000
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expanded := cal.Expand(
+		time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC),
+	)
+
+	assert.Len(t, cal.Events, 3, "the original calendar must not be modified")
+	if !assert.Len(t, expanded.Events, 4) {
+		return
+	}
+
+	first := expanded.Events[0]
+	assert.Equal(t, "weekly", first.UID)
+	assert.Equal(t, "Weekly", first.Summary)
+	assert.Equal(t, time.Date(2020, time.January, 6, 10, 0, 0, 0, berlin), first.Start)
+	assert.Equal(t, time.Date(2020, time.January, 6, 11, 0, 0, 0, berlin), first.End)
+	assert.Equal(t, first.Start, first.RecurrenceID)
+	assert.Nil(t, first.Recurrence)
+	assert.Empty(t, first.RecurrenceRules)
+	assert.Empty(t, first.ExDates)
+	tzid := parse.Parameters{"TZID": {"Europe/Berlin"}}
+	assert.Equal(t, []parse.Property{
+		testutil.Property("UID", "weekly", nil),
+		testutil.Property("SUMMARY", "Weekly", nil),
+		testutil.Property("RECURRENCE-ID", "20200106T100000", tzid),
+		testutil.Property("DTSTART", "20200106T100000", tzid),
+		testutil.Property("DTEND", "20200106T110000", tzid),
+	}, first.Properties)
+
+	moved := expanded.Events[1]
+	assert.Equal(t, "Moved", moved.Summary)
+	assert.Equal(t, time.Date(2020, time.January, 20, 10, 0, 0, 0, berlin), moved.RecurrenceID)
+	assert.Equal(t, time.Date(2020, time.January, 21, 14, 0, 0, 0, berlin), moved.Start)
+
+	last := expanded.Events[2]
+	assert.Equal(t, "Weekly", last.Summary)
+	assert.Equal(t, time.Date(2020, time.January, 27, 10, 0, 0, 0, berlin), last.Start)
+	assert.Equal(t, time.Date(2020, time.January, 27, 11, 0, 0, 0, berlin), last.End)
+
+	assert.Equal(t, "single", expanded.Events[3].UID)
+}
+
+func TestCalendar_Expand_properties(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:daily
+DTSTART;VALUE=DATE:20200101
+DURATION:P2D
+RRULE:FREQ=DAILY;COUNT=2
+END:VEVENT
+BEGIN:VEVENT
+UID:utc
+DTSTART:20200101T100000Z
+DTEND:20200101T110000Z
+RDATE:20200105T100000Z
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expanded := cal.Expand(
+		time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC),
+	)
+
+	if !assert.Len(t, expanded.Events, 4) {
+		return
+	}
+
+	date := parse.Parameters{"VALUE": {"DATE"}}
+	assert.Equal(t, []parse.Property{
+		testutil.Property("UID", "daily", nil),
+		testutil.Property("RECURRENCE-ID", "20200102", date),
+		testutil.Property("DTSTART", "20200102", date),
+		testutil.Property("DURATION", "P2D", nil),
+	}, expanded.Events[1].Properties)
+	assert.Equal(t, time.Date(2020, time.January, 4, 0, 0, 0, 0, time.Local), expanded.Events[1].End)
+
+	assert.Equal(t, []parse.Property{
+		testutil.Property("UID", "utc", nil),
+		testutil.Property("RECURRENCE-ID", "20200105T100000Z", nil),
+		testutil.Property("DTSTART", "20200105T100000Z", nil),
+		testutil.Property("DTEND", "20200105T110000Z", nil),
+	}, expanded.Events[3].Properties)
+
+	// the params of the occurrences are not shared
+	expanded.Events[0].Properties[1].Params["VALUE"][0] = "DATE-TIME"
+	assert.Equal(t, "DATE", expanded.Events[1].Properties[1].Params["VALUE"][0])
+	assert.Equal(t, "DATE", cal.Events[0].Properties[1].Params["VALUE"][0])
+}
+
+func TestCalendar_Expand_thisAndFuture(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:weekly
+SUMMARY:Weekly
+DTSTART:20200106T100000Z
+DTEND:20200106T110000Z
+RRULE:FREQ=WEEKLY;COUNT=4
+END:VEVENT
+BEGIN:VEVENT
+UID:weekly
+SUMMARY:Renamed
+RECURRENCE-ID;RANGE=THISANDFUTURE:20200113T100000Z
+DTSTART:20200113T120000Z
+DTEND:20200113T130000Z
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "THISANDFUTURE", cal.Events[1].RecurrenceRange)
+
+	expanded := cal.Expand(
+		time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC),
+	)
+
+	if !assert.Len(t, expanded.Events, 4) {
+		return
+	}
+
+	assert.Equal(t, "Weekly", expanded.Events[0].Summary)
+	assert.Equal(t, time.Date(2020, time.January, 6, 10, 0, 0, 0, time.UTC), expanded.Events[0].Start)
+
+	for i, day := range []int{13, 20, 27} {
+		evt := expanded.Events[i+1]
+		assert.Equal(t, "Renamed", evt.Summary)
+		assert.Equal(t, time.Date(2020, time.January, day, 10, 0, 0, 0, time.UTC), evt.RecurrenceID)
+		assert.Equal(t, time.Date(2020, time.January, day, 12, 0, 0, 0, time.UTC), evt.Start)
+		assert.Equal(t, time.Date(2020, time.January, day, 13, 0, 0, 0, time.UTC), evt.End)
+	}
+
+	last := expanded.Events[3]
+	assert.Equal(t, "", last.RecurrenceRange)
+	assert.Equal(t, []parse.Property{
+		testutil.Property("UID", "weekly", nil),
+		testutil.Property("SUMMARY", "Renamed", nil),
+		testutil.Property("RECURRENCE-ID", "20200127T100000Z", nil),
+		testutil.Property("DTSTART", "20200127T120000Z", nil),
+		testutil.Property("DTEND", "20200127T130000Z", nil),
+	}, last.Properties)
+}
+
+func TestCalendar_Expand_crossZone(t *testing.T) {
+	london := testutil.LoadLocation("Europe/London")
+
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:flight
+DTSTART;TZID=America/New_York:20200106T190000
+DTEND;TZID=Europe/London:20200107T000000
+RRULE:FREQ=WEEKLY;COUNT=2
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expanded := cal.Expand(
+		time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC),
+	)
+	if !assert.Len(t, expanded.Events, 2) {
+		return
+	}
+
+	second := expanded.Events[1]
+	assert.Equal(t, time.Date(2020, time.January, 14, 0, 0, 0, 0, london), second.End)
+	dtend, _ := second.Property("DTEND")
+	assert.Equal(t, "20200114T000000", dtend.Value)
+}
+
+func TestCalendar_Upcoming(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:past
+DTSTART:20191201T100000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:far
+DTSTART:20230101T100000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:monthly
+DTSTART:20191115T090000Z
+RRULE:FREQ=MONTHLY;COUNT=4
+END:VEVENT
+BEGIN:VEVENT
+UID:soon
+DTSTART:20200110T100000Z
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	type upcoming struct {
+		uid   string
+		start time.Time
+	}
+
+	var got []upcoming
+	for _, evt := range cal.Upcoming(now, 4) {
+		got = append(got, upcoming{evt.UID, evt.Start})
+	}
+
+	assert.Equal(t, []upcoming{
+		{"soon", time.Date(2020, time.January, 10, 10, 0, 0, 0, time.UTC)},
+		{"monthly", time.Date(2020, time.January, 15, 9, 0, 0, 0, time.UTC)},
+		{"monthly", time.Date(2020, time.February, 15, 9, 0, 0, 0, time.UTC)},
+		{"far", time.Date(2023, time.January, 1, 10, 0, 0, 0, time.UTC)},
+	}, got)
+
+	assert.Len(t, cal.Upcoming(now, 10), 4)
+	assert.Empty(t, cal.Upcoming(now, 0))
+}
+
+func TestCalendar_Upcoming_thisAndFuture(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:weekly
+SUMMARY:Weekly
+DTSTART:20200106T100000Z
+RRULE:FREQ=WEEKLY;COUNT=4
+END:VEVENT
+BEGIN:VEVENT
+UID:weekly
+SUMMARY:Renamed
+RECURRENCE-ID;RANGE=THISANDFUTURE:20200113T100000Z
+DTSTART:20200113T120000Z
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the occurrence of January 20 begins at 10:00, but is shifted to 12:00
+	events := cal.Upcoming(time.Date(2020, time.January, 20, 11, 0, 0, 0, time.UTC), 5)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, "Renamed", events[0].Summary)
+		assert.Equal(t, time.Date(2020, time.January, 20, 12, 0, 0, 0, time.UTC), events[0].Start)
+		assert.Equal(t, time.Date(2020, time.January, 27, 12, 0, 0, 0, time.UTC), events[1].Start)
+	}
+}
+
+func TestCalendar_Upcoming_farFuture(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:future
+DTSTART:25000101T100000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:yearly
+DTSTART:20200601T100000Z
+RRULE:FREQ=YEARLY
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	events := cal.Upcoming(now, 300)
+	if assert.Len(t, events, 300) {
+		assert.Equal(t, time.Date(2319, time.June, 1, 10, 0, 0, 0, time.UTC), events[299].Start)
+	}
+
+	events = cal.Upcoming(time.Date(2400, time.January, 1, 0, 0, 0, 0, time.UTC), 101)
+	if assert.Len(t, events, 101) {
+		assert.Equal(t, "future", events[100].UID)
+		assert.Equal(t, time.Date(2500, time.January, 1, 10, 0, 0, 0, time.UTC), events[100].Start)
+	}
+
+	cal.Events = cal.Events[:1]
+	events = cal.Upcoming(now, 1)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "future", events[0].UID)
+	}
+}
@@ -0,0 +1,195 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// eventField sets the fields of evt that are derived from prop.
+type eventField func(p *Parser, evt *Event, prop Property) error
+
+// calendarFields are the calendar properties that are lifted to typed fields.
+var calendarFields = map[string]bool{
+	"VERSION":  true,
+	"METHOD":   true,
+	"PRODID":   true,
+	"CALSCALE": true,
+
+	"X-WR-RELCALID": true,
+}
+
+// implicitEventFields are the event properties that are mapped to typed fields
+// without a handler in defaultEventFields.
+var implicitEventFields = map[string]bool{
+	"DURATION": true,
+}
+
+// alarmFields are the alarm properties that are lifted to typed fields.
+var alarmFields = map[string]bool{
+	"ACTION":       true,
+	"TRIGGER":      true,
+	"PROXIMITY":    true,
+	"ACKNOWLEDGED": true,
+	"REPEAT":       true,
+	"DURATION":     true,
+}
+
+// defaultEventFields are the handlers of the event properties that are
+// lifted to typed fields. They can be replaced by the FieldMapper option.
+var defaultEventFields = map[string]eventField{
+	"UID": func(p *Parser, evt *Event, prop Property) error {
+		evt.UID = prop.Value
+		return nil
+	},
+	"DTSTART": func(p *Parser, evt *Event, prop Property) error {
+		if !isTimeValue(prop) {
+			return nil
+		}
+		t, err := p.parseTime(prop)
+		if err != nil {
+			return err
+		}
+		evt.Start = t
+		return nil
+	},
+	"DTEND": func(p *Parser, evt *Event, prop Property) error {
+		if !isTimeValue(prop) {
+			return nil
+		}
+		t, err := p.parseDTEND(prop)
+		if err != nil {
+			return err
+		}
+		evt.End = t
+		return nil
+	},
+	"DTSTAMP": func(p *Parser, evt *Event, prop Property) error {
+		if !isTimeValue(prop) {
+			return nil
+		}
+		t, err := p.parseTime(prop)
+		if err != nil {
+			return err
+		}
+		evt.Timestamp = t
+		return nil
+	},
+	"RECURRENCE-ID": func(p *Parser, evt *Event, prop Property) error {
+		if !isTimeValue(prop) {
+			return nil
+		}
+		t, err := p.parseTime(prop)
+		if err != nil {
+			return err
+		}
+		evt.RecurrenceID = t
+		if rng, ok := prop.Params.First("RANGE"); ok {
+			evt.RecurrenceRange = strings.ToUpper(rng)
+		}
+		return nil
+	},
+	"SUMMARY": func(p *Parser, evt *Event, prop Property) error {
+		text := localizedText(prop)
+		evt.Summaries = append(evt.Summaries, text)
+		if isDefaultText(evt.Summaries, evt.SummaryLanguage) {
+			evt.Summary, evt.SummaryLanguage = text.Value, text.Language
+		}
+		return nil
+	},
+	"DESCRIPTION": func(p *Parser, evt *Event, prop Property) error {
+		text := localizedText(prop)
+		evt.Descriptions = append(evt.Descriptions, text)
+		if isDefaultText(evt.Descriptions, evt.DescriptionLanguage) {
+			evt.Description, evt.DescriptionLanguage = text.Value, text.Language
+			evt.DescriptionAltRep, _ = prop.Params.First("ALTREP")
+		}
+		return nil
+	},
+	"LOCATION": func(p *Parser, evt *Event, prop Property) error {
+		evt.Location = textValue(prop)
+		evt.LocationAltRep, _ = prop.Params.First("ALTREP")
+		return nil
+	},
+	"GEO": func(p *Parser, evt *Event, prop Property) error {
+		geo, err := p.parseGeo(prop)
+		if err != nil {
+			return fmt.Errorf("%s: %w", prop.Name, err)
+		}
+		evt.Geo = &geo
+		return nil
+	},
+	"CATEGORIES": func(p *Parser, evt *Event, prop Property) error {
+		for _, cat := range splitText(prop.Value) {
+			evt.Categories = append(evt.Categories, textValue(Property{Name: prop.Name, Params: prop.Params, Value: cat}))
+		}
+		return nil
+	},
+	"CONFERENCE": func(p *Parser, evt *Event, prop Property) error {
+		evt.Conferences = append(evt.Conferences, parseConference(prop))
+		return nil
+	},
+	"ATTENDEE": func(p *Parser, evt *Event, prop Property) error {
+		evt.Attendees = append(evt.Attendees, p.parseAttendee(prop))
+		return nil
+	},
+	"ORGANIZER": func(p *Parser, evt *Event, prop Property) error {
+		evt.Organizer = p.parseOrganizer(prop)
+		return nil
+	},
+	"URL": func(p *Parser, evt *Event, prop Property) error {
+		evt.URL = textValue(prop)
+		return nil
+	},
+	"ATTACH": func(p *Parser, evt *Event, prop Property) error {
+		evt.Attachments = append(evt.Attachments, parseAttachment(prop))
+		return nil
+	},
+	"REQUEST-STATUS": func(p *Parser, evt *Event, prop Property) error {
+		evt.RequestStatus = append(evt.RequestStatus, parseRequestStatus(prop))
+		return nil
+	},
+	"RRULE": func(p *Parser, evt *Event, prop Property) error {
+		dtstart, _ := evt.Property("DTSTART")
+		rule, err := p.parseRecurrenceRule(prop.Value, dtstart)
+		if err != nil {
+			return fmt.Errorf("%s: %w", prop.Name, err)
+		}
+
+		evt.RecurrenceRules = append(evt.RecurrenceRules, rule)
+		if evt.Recurrence == nil {
+			evt.Recurrence = &rule
+		}
+		return nil
+	},
+	"EXRULE": func(p *Parser, evt *Event, prop Property) error {
+		dtstart, _ := evt.Property("DTSTART")
+		rule, err := p.parseRecurrenceRule(prop.Value, dtstart)
+		if err != nil {
+			return fmt.Errorf("%s: %w", prop.Name, err)
+		}
+		evt.ExceptionRules = append(evt.ExceptionRules, rule)
+		return nil
+	},
+	"RDATE": func(p *Parser, evt *Event, prop Property) error {
+		if !isTimeValue(prop) && prop.ValueType() != "PERIOD" {
+			return nil
+		}
+		times, err := p.parseTimeList(prop)
+		if err != nil {
+			return fmt.Errorf("%s: %w", prop.Name, err)
+		}
+		evt.RDates = append(evt.RDates, times...)
+		return nil
+	},
+	"EXDATE": func(p *Parser, evt *Event, prop Property) error {
+		if !isTimeValue(prop) {
+			return nil
+		}
+		times, err := p.parseTimeList(prop)
+		if err != nil {
+			return fmt.Errorf("%s: %w", prop.Name, err)
+		}
+		evt.ExDates = append(evt.ExDates, times...)
+		return nil
+	},
+}
@@ -0,0 +1,674 @@
+package parse
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// maxEmptyYears is the number of years without any occurrence after which a
+// recurrence rule is considered exhausted. The Gregorian calendar repeats every
+// 400 years, so this stops rules that can never match (e.g.
+// "BYMONTH=2;BYMONTHDAY=30") from looping forever, regardless of their FREQ.
+const maxEmptyYears = 400
+
+// Occurrences returns the start times of the event's occurrences that begin
+// within [from, to), in chronological order. The occurrences are the union of
+// DTSTART, all RRULEs and all RDATEs, excluding the EXDATEs and all
+// occurrences of the EXRULEs. EXDATEs are compared to the occurrences by
+// instant, so they may be given in any timezone.
+func (evt Event) Occurrences(from, to time.Time) []time.Time {
+	occs, _ := evt.OccurrencesCtx(context.Background(), from, to)
+	return occs
+}
+
+// OccurrencesCtx returns the occurrences like Occurrences, but stops when ctx
+// is canceled, which bounds the time spent expanding recurrences over wide
+// ranges. If ctx is canceled, OccurrencesCtx returns the occurrences that have
+// been computed so far and ctx.Err().
+func (evt Event) OccurrencesCtx(ctx context.Context, from, to time.Time) ([]time.Time, error) {
+	var occs []time.Time
+	it := evt.recurrenceSet()
+	for {
+		select {
+		case <-ctx.Done():
+			return occs, ctx.Err()
+		default:
+		}
+
+		t, ok := it.next()
+		if !ok || !t.Before(to) {
+			return occs, nil
+		}
+
+		if !t.Before(from) {
+			occs = append(occs, t)
+		}
+	}
+}
+
+// NextOccurrence returns the start time of the event's first occurrence that
+// begins strictly after the given time. ok is false if there is no such
+// occurrence, e.g. because the recurrence has ended. The occurrences are
+// computed lazily, so this also works for infinite recurrences.
+func (evt Event) NextOccurrence(after time.Time) (time.Time, bool) {
+	it := evt.recurrenceSet()
+	for {
+		t, ok := it.next()
+		if !ok {
+			return time.Time{}, false
+		}
+
+		if t.After(after) {
+			return t, true
+		}
+	}
+}
+
+type occurrenceIterator interface {
+	// next returns the next occurrence or false if there are no more occurrences.
+	next() (time.Time, bool)
+}
+
+// recurrenceSet merges the occurrences of multiple iterators in chronological
+// order and removes duplicates & excluded occurrences.
+type recurrenceSet struct {
+	include []*peekIterator
+	exclude []*peekIterator
+	exdates map[int64]bool
+	last    time.Time
+	started bool
+}
+
+func (evt Event) recurrenceSet() *recurrenceSet {
+	set := &recurrenceSet{exdates: make(map[int64]bool)}
+	if evt.Start.IsZero() {
+		return set
+	}
+
+	rdates := append([]time.Time{evt.Start}, evt.RDates...)
+	sort.Slice(rdates, func(a, b int) bool { return rdates[a].Before(rdates[b]) })
+	set.include = append(set.include, newPeekIterator(&listIterator{rdates}))
+
+	for _, rule := range evt.RecurrenceRules {
+		set.include = append(set.include, newPeekIterator(newRuleIterator(rule, evt.Start)))
+	}
+
+	for _, rule := range evt.ExceptionRules {
+		set.exclude = append(set.exclude, newPeekIterator(newRuleIterator(rule, evt.Start)))
+	}
+
+	for _, t := range evt.ExDates {
+		set.exdates[t.Unix()] = true
+	}
+
+	return set
+}
+
+func (set *recurrenceSet) next() (time.Time, bool) {
+	for {
+		var earliest *peekIterator
+		for _, it := range set.include {
+			if it.ok && (earliest == nil || it.head.Before(earliest.head)) {
+				earliest = it
+			}
+		}
+
+		if earliest == nil {
+			return time.Time{}, false
+		}
+
+		t := earliest.head
+		earliest.advance()
+
+		if set.started && !t.After(set.last) {
+			continue
+		}
+		set.started = true
+		set.last = t
+
+		if set.excluded(t) {
+			continue
+		}
+
+		return t, true
+	}
+}
+
+func (set *recurrenceSet) excluded(t time.Time) bool {
+	if set.exdates[t.Unix()] {
+		return true
+	}
+
+	for _, it := range set.exclude {
+		for it.ok && it.head.Before(t) {
+			it.advance()
+		}
+
+		if it.ok && it.head.Equal(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type peekIterator struct {
+	it   occurrenceIterator
+	head time.Time
+	ok   bool
+}
+
+func newPeekIterator(it occurrenceIterator) *peekIterator {
+	p := &peekIterator{it: it}
+	p.advance()
+	return p
+}
+
+func (p *peekIterator) advance() {
+	p.head, p.ok = p.it.next()
+}
+
+// listIterator iterates over a sorted list of times.
+type listIterator struct {
+	times []time.Time
+}
+
+func (it *listIterator) next() (time.Time, bool) {
+	if len(it.times) == 0 {
+		return time.Time{}, false
+	}
+	t := it.times[0]
+	it.times = it.times[1:]
+	return t, true
+}
+
+// ruleIterator iterates over the occurrences of a single RecurrenceRule.
+// Occurrences are generated one period (year, month, week, ...) at a time.
+type ruleIterator struct {
+	rule    RecurrenceRule
+	dtstart time.Time
+	period  int
+	// first is the first day (civil date) of the last expanded period
+	first time.Time
+	// lastMatch is the first day (civil date) of the last period with occurrences
+	lastMatch time.Time
+	count     int
+	buf       []time.Time
+	done      bool
+}
+
+func newRuleIterator(rule RecurrenceRule, dtstart time.Time) *ruleIterator {
+	if rule.Interval < 1 {
+		rule.Interval = 1
+	}
+	it := &ruleIterator{rule: rule, dtstart: dtstart, lastMatch: civilDate(dtstart.Date())}
+
+	// a second of 60 is never the second of a SECONDLY period
+	if rule.Frequency == Secondly && len(rule.BySecond) > 0 && len(validSeconds(rule.BySecond)) == 0 {
+		it.done = true
+	}
+
+	return it
+}
+
+func (it *ruleIterator) next() (time.Time, bool) {
+	for len(it.buf) == 0 {
+		if it.done {
+			return time.Time{}, false
+		}
+
+		it.buf = it.expandPeriod(it.period)
+
+		if len(it.buf) > 0 {
+			it.lastMatch = it.first
+			it.period++
+			continue
+		}
+
+		if it.first.After(it.lastMatch.AddDate(maxEmptyYears, 0, 0)) {
+			it.done = true
+		}
+		it.period = it.nextPeriod(it.period)
+	}
+
+	t := it.buf[0]
+	it.buf = it.buf[1:]
+
+	if !it.rule.Until.IsZero() && t.After(it.rule.Until) {
+		it.done = true
+		it.buf = nil
+		return time.Time{}, false
+	}
+
+	// DTSTART always counts as the first occurrence, even if it doesn't match the rule
+	// (https://tools.ietf.org/html/rfc5545#section-3.8.5.3)
+	if it.count == 0 && !t.Equal(it.dtstart) {
+		if it.count++; it.rule.Count > 0 && it.count >= it.rule.Count {
+			it.done = true
+			it.buf = nil
+			return time.Time{}, false
+		}
+	}
+
+	it.count++
+	if it.rule.Count > 0 && it.count >= it.rule.Count {
+		it.done = true
+		it.buf = nil
+	}
+
+	return t, true
+}
+
+// nextPeriod returns the period that follows the empty period k. Sub-daily
+// rules skip the periods of days, hours and minutes that cannot match the
+// rule, so that rules like "FREQ=SECONDLY;BYHOUR=9" don't expand every second.
+func (it *ruleIterator) nextPeriod(k int) int {
+	if !isSubDaily(it.rule.Frequency) {
+		return k + 1
+	}
+
+	base, step := it.subDailyPeriods()
+	start := time.Unix(base.Unix()+int64(k)*step, 0).In(base.Location())
+	candidate := it.nextCandidate(start)
+	if !candidate.After(start) {
+		return k + 1
+	}
+
+	// the first period that starts at or after the candidate
+	next := (candidate.Unix() - base.Unix() + step - 1) / step
+	if next <= int64(k) {
+		return k + 1
+	}
+	return int(next)
+}
+
+func isSubDaily(freq Frequency) bool {
+	return freq == Hourly || freq == Minutely || freq == Secondly
+}
+
+// nextCandidate returns the earliest time at or after t that may be an
+// occurrence of the sub-daily rule, based on its day, BYHOUR, BYMINUTE and
+// BYSECOND rules.
+func (it *ruleIterator) nextCandidate(t time.Time) time.Time {
+	rule := it.rule
+	loc := t.Location()
+	y, m, d := t.Date()
+	h, minute, sec := t.Clock()
+
+	if !it.matchDay(civilDate(y, m, d), y) {
+		return time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+	}
+
+	if len(rule.ByHour) > 0 && !containsInt(rule.ByHour, h) {
+		if next, ok := nextInt(rule.ByHour, h); ok {
+			return time.Date(y, m, d, next, 0, 0, 0, loc)
+		}
+		return time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+	}
+
+	if rule.Frequency == Hourly {
+		return t
+	}
+
+	if len(rule.ByMinute) > 0 && !containsInt(rule.ByMinute, minute) {
+		if next, ok := nextInt(rule.ByMinute, minute); ok {
+			return time.Date(y, m, d, h, next, 0, 0, loc)
+		}
+		return time.Date(y, m, d, h+1, 0, 0, 0, loc)
+	}
+
+	if rule.Frequency == Minutely {
+		return t
+	}
+
+	if seconds := validSeconds(rule.BySecond); len(seconds) > 0 && !containsInt(seconds, sec) {
+		if next, ok := nextInt(seconds, sec); ok {
+			return time.Date(y, m, d, h, minute, next, 0, loc)
+		}
+		return time.Date(y, m, d, h, minute+1, 0, 0, loc)
+	}
+
+	return t
+}
+
+// subDailyPeriods returns the start of the first period of a sub-daily rule
+// and the length of its periods in seconds.
+func (it *ruleIterator) subDailyPeriods() (time.Time, int64) {
+	start := it.dtstart
+	loc := start.Location()
+	y, m, d := start.Date()
+
+	switch it.rule.Frequency {
+	case Hourly:
+		return time.Date(y, m, d, start.Hour(), 0, 0, 0, loc), 3600 * int64(it.rule.Interval)
+	case Minutely:
+		return time.Date(y, m, d, start.Hour(), start.Minute(), 0, 0, loc), 60 * int64(it.rule.Interval)
+	default:
+		return time.Date(y, m, d, start.Hour(), start.Minute(), start.Second(), 0, loc), int64(it.rule.Interval)
+	}
+}
+
+// expandPeriod returns the sorted occurrences within the k-th period of the rule.
+func (it *ruleIterator) expandPeriod(k int) []time.Time {
+	rule := it.rule
+	start := it.dtstart
+	loc := start.Location()
+	n := k * rule.Interval
+	y, m, d := start.Date()
+
+	// first & last are civil dates (UTC midnight) that limit the days of the period
+	var first, last time.Time
+	// periodStart is the start of the period for sub-daily frequencies
+	var periodStart time.Time
+
+	switch rule.Frequency {
+	case Yearly:
+		first = civilDate(y+n, time.January, 1)
+		last = civilDate(y+n, time.December, 31)
+		if len(rule.ByWeekNo) > 0 {
+			// weeks may begin in the previous or end in the next year
+			first = first.AddDate(0, 0, -7)
+			last = last.AddDate(0, 0, 7)
+		}
+	case Monthly:
+		first = civilDate(y, m+time.Month(n), 1)
+		last = first.AddDate(0, 1, -1)
+	case Weekly:
+		day := civilDate(y, m, d)
+		offset := (int(day.Weekday()) - int(rule.WeekStart) + 7) % 7
+		first = day.AddDate(0, 0, 7*n-offset)
+		last = first.AddDate(0, 0, 6)
+	case Daily:
+		first = civilDate(y, m, d+n)
+		last = first
+	default:
+		// computed in seconds, because a time.Duration overflows after 292 years
+		base, step := it.subDailyPeriods()
+		periodStart = time.Unix(base.Unix()+int64(k)*step, 0).In(loc)
+		py, pm, pd := periodStart.Date()
+		first = civilDate(py, pm, pd)
+		last = first
+	}
+
+	it.first = first
+	if first.Year() > 9999 {
+		it.done = true
+		return nil
+	}
+
+	var days []time.Time
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		if it.matchDay(day, y+n) {
+			days = append(days, day)
+		}
+	}
+
+	if len(days) == 0 {
+		return nil
+	}
+
+	var occs []time.Time
+	if !periodStart.IsZero() {
+		occs = it.subDailyOccurrences(periodStart)
+	} else {
+		hours := sortedOr(rule.ByHour, start.Hour())
+		minutes := sortedOr(rule.ByMinute, start.Minute())
+		seconds := sortedOr(rule.BySecond, start.Second())
+		for _, day := range days {
+			for _, h := range hours {
+				for _, minute := range minutes {
+					for _, sec := range seconds {
+						occs = append(occs, time.Date(day.Year(), day.Month(), day.Day(), h, minute, sec, 0, loc))
+					}
+				}
+			}
+		}
+	}
+
+	sortTimes(occs)
+	occs = applySetPos(occs, rule.BySetPos)
+
+	// drop occurrences before DTSTART
+	i := 0
+	for i < len(occs) && occs[i].Before(start) {
+		i++
+	}
+
+	return occs[i:]
+}
+
+// subDailyOccurrences returns the occurrences within the hour, minute or
+// second that begins at periodStart.
+func (it *ruleIterator) subDailyOccurrences(periodStart time.Time) []time.Time {
+	rule := it.rule
+
+	if len(rule.ByHour) > 0 && !containsInt(rule.ByHour, periodStart.Hour()) {
+		return nil
+	}
+
+	switch rule.Frequency {
+	case Hourly:
+		var occs []time.Time
+		for _, minute := range sortedOr(rule.ByMinute, it.dtstart.Minute()) {
+			for _, sec := range sortedOr(rule.BySecond, it.dtstart.Second()) {
+				occs = append(occs, periodStart.Add(time.Duration(minute)*time.Minute+time.Duration(sec)*time.Second))
+			}
+		}
+		return occs
+	case Minutely:
+		if len(rule.ByMinute) > 0 && !containsInt(rule.ByMinute, periodStart.Minute()) {
+			return nil
+		}
+
+		var occs []time.Time
+		for _, sec := range sortedOr(rule.BySecond, it.dtstart.Second()) {
+			occs = append(occs, periodStart.Add(time.Duration(sec)*time.Second))
+		}
+		return occs
+	default:
+		if len(rule.ByMinute) > 0 && !containsInt(rule.ByMinute, periodStart.Minute()) {
+			return nil
+		}
+
+		if len(rule.BySecond) > 0 && !containsInt(rule.BySecond, periodStart.Second()) {
+			return nil
+		}
+
+		return []time.Time{periodStart}
+	}
+}
+
+// matchDay determines if the civil date day is part of the recurrence.
+// year is the year of the current period (used by BYWEEKNO).
+func (it *ruleIterator) matchDay(day time.Time, year int) bool {
+	rule := it.rule
+
+	if len(rule.ByMonth) > 0 && !containsInt(rule.ByMonth, int(day.Month())) {
+		return false
+	}
+
+	if len(rule.ByWeekNo) > 0 && rule.Frequency == Yearly {
+		wy, week := weekNumber(day, rule.WeekStart)
+		if wy != year || !matchOrdinal(rule.ByWeekNo, week, weeksInYear(wy, rule.WeekStart)) {
+			return false
+		}
+	}
+
+	if len(rule.ByYearDay) > 0 && !matchOrdinal(rule.ByYearDay, day.YearDay(), daysInYear(day.Year())) {
+		return false
+	}
+
+	if len(rule.ByMonthDay) > 0 && !matchOrdinal(rule.ByMonthDay, day.Day(), daysInMonth(day.Year(), day.Month())) {
+		return false
+	}
+
+	if len(rule.ByDay) > 0 && !it.matchWeekday(day) {
+		return false
+	}
+
+	if len(rule.ByWeekNo) > 0 || len(rule.ByYearDay) > 0 || len(rule.ByMonthDay) > 0 || len(rule.ByDay) > 0 {
+		return true
+	}
+
+	// without explicit day rules, the day is taken from DTSTART
+	switch rule.Frequency {
+	case Yearly:
+		if len(rule.ByMonth) == 0 && day.Month() != it.dtstart.Month() {
+			return false
+		}
+		return day.Day() == it.dtstart.Day()
+	case Monthly:
+		return day.Day() == it.dtstart.Day()
+	case Weekly:
+		return day.Weekday() == it.dtstart.Weekday()
+	default:
+		return true
+	}
+}
+
+func (it *ruleIterator) matchWeekday(day time.Time) bool {
+	rule := it.rule
+	for _, wd := range rule.ByDay {
+		if wd.Weekday != day.Weekday() {
+			continue
+		}
+
+		if wd.N == 0 {
+			return true
+		}
+
+		var pos, size int
+		switch {
+		case rule.Frequency == Monthly || (rule.Frequency == Yearly && len(rule.ByMonth) > 0):
+			pos, size = day.Day(), daysInMonth(day.Year(), day.Month())
+		case rule.Frequency == Yearly:
+			pos, size = day.YearDay(), daysInYear(day.Year())
+		default:
+			// ordinals are only valid for MONTHLY & YEARLY rules
+			return true
+		}
+
+		// the n-th weekday counted from the start or (if negative) from the end
+		if (wd.N > 0 && (pos-1)/7+1 == wd.N) || (wd.N < 0 && (size-pos)/7+1 == -wd.N) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchOrdinal determines if one of the ordinals refers to the (1-based)
+// position pos within a set of the given size. Negative ordinals count from the end.
+func matchOrdinal(ordinals []int, pos, size int) bool {
+	for _, n := range ordinals {
+		if n > 0 && n == pos {
+			return true
+		}
+
+		if n < 0 && size+n+1 == pos {
+			return true
+		}
+	}
+	return false
+}
+
+func applySetPos(occs []time.Time, positions []int) []time.Time {
+	if len(positions) == 0 || len(occs) == 0 {
+		return occs
+	}
+
+	var res []time.Time
+	for _, pos := range positions {
+		i := pos - 1
+		if pos < 0 {
+			i = len(occs) + pos
+		}
+
+		if i >= 0 && i < len(occs) {
+			res = append(res, occs[i])
+		}
+	}
+
+	sortTimes(res)
+
+	// remove duplicates
+	uniq := res[:0]
+	for i, t := range res {
+		if i == 0 || !t.Equal(res[i-1]) {
+			uniq = append(uniq, t)
+		}
+	}
+
+	return uniq
+}
+
+// weekNumber returns the week-numbering year and the number of the week that
+// contains day. Week 1 is the first week with at least 4 days in the year.
+func weekNumber(day time.Time, wkst time.Weekday) (year, week int) {
+	offset := (int(day.Weekday()) - int(wkst) + 7) % 7
+	fourth := day.AddDate(0, 0, 3-offset)
+	return fourth.Year(), (fourth.YearDay()-1)/7 + 1
+}
+
+func weeksInYear(year int, wkst time.Weekday) int {
+	// December 28 is always in the last week of the year
+	_, week := weekNumber(civilDate(year, time.December, 28), wkst)
+	return week
+}
+
+func civilDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func daysInYear(year int) int {
+	return civilDate(year, time.December, 31).YearDay()
+}
+
+func daysInMonth(year int, month time.Month) int {
+	return civilDate(year, month+1, 0).Day()
+}
+
+// nextInt returns the smallest of nums that is greater than num.
+func nextInt(nums []int, num int) (int, bool) {
+	next, ok := 0, false
+	for _, n := range nums {
+		if n > num && (!ok || n < next) {
+			next, ok = n, true
+		}
+	}
+	return next, ok
+}
+
+// validSeconds returns the seconds of a BYSECOND rule without the leap second 60.
+func validSeconds(seconds []int) []int {
+	var valid []int
+	for _, sec := range seconds {
+		if sec < 60 {
+			valid = append(valid, sec)
+		}
+	}
+	return valid
+}
+
+func containsInt(nums []int, num int) bool {
+	for _, n := range nums {
+		if n == num {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedOr returns a sorted copy of nums or a slice with only def if nums is empty.
+func sortedOr(nums []int, def int) []int {
+	if len(nums) == 0 {
+		return []int{def}
+	}
+	sorted := append([]int(nil), nums...)
+	sort.Ints(sorted)
+	return sorted
+}
+
+func sortTimes(times []time.Time) {
+	sort.Slice(times, func(a, b int) bool { return times[a].Before(times[b]) })
+}
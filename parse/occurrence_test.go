@@ -0,0 +1,421 @@
+package parse_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bounoable/ical/internal/testutil"
+	"github.com/bounoable/ical/lex"
+	"github.com/bounoable/ical/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvent_Occurrences(t *testing.T) {
+	ny := testutil.LoadLocation("America/New_York")
+
+	tests := []struct {
+		name     string
+		body     string
+		from     time.Time
+		to       time.Time
+		expected []time.Time
+	}{
+		{
+			name: "without recurrence",
+			body: `DTSTART:20200101T100000Z`,
+			from: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2020, time.January, 1, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "multiple RDATEs",
+			body: `DTSTART:20200101T100000Z
+RDATE:20200105T100000Z,20200103T100000Z
+RDATE;VALUE=PERIOD:20200110T120000Z/PT1H`,
+			from: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2020, time.January, 1, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 3, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 5, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 10, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "weekly with interval & weekdays",
+			body: `DTSTART;TZID=America/New_York:20200302T090000
+RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=6`,
+			from: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2020, time.March, 2, 9, 0, 0, 0, ny),
+				time.Date(2020, time.March, 4, 9, 0, 0, 0, ny),
+				// DST begins on March 8, the wall clock time is kept
+				time.Date(2020, time.March, 16, 9, 0, 0, 0, ny),
+				time.Date(2020, time.March, 18, 9, 0, 0, 0, ny),
+				time.Date(2020, time.March, 30, 9, 0, 0, 0, ny),
+				time.Date(2020, time.April, 1, 9, 0, 0, 0, ny),
+			},
+		},
+		{
+			name: "range limits the occurrences",
+			body: `DTSTART:20200101T100000Z
+RRULE:FREQ=DAILY`,
+			from: time.Date(2020, time.February, 1, 10, 0, 0, 0, time.UTC),
+			to:   time.Date(2020, time.February, 4, 10, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2020, time.February, 1, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.February, 2, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.February, 3, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "daily until",
+			body: `DTSTART:20200101T100000Z
+RRULE:FREQ=DAILY;INTERVAL=10;UNTIL=20200131T100000Z`,
+			from: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2020, time.January, 1, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 11, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 21, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 31, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "monthly on the last friday",
+			body: `DTSTART:20200131T100000Z
+RRULE:FREQ=MONTHLY;BYDAY=-1FR;COUNT=3`,
+			from: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2020, time.January, 31, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.February, 28, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.March, 27, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "monthly on the last workday",
+			body: `DTSTART:20200131T100000Z
+RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1;COUNT=3`,
+			from: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2020, time.January, 31, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.February, 28, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.March, 31, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "monthly on the 31st skips shorter months",
+			body: `DTSTART:20200131T100000Z
+RRULE:FREQ=MONTHLY;COUNT=3`,
+			from: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2020, time.January, 31, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.March, 31, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.May, 31, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "yearly on monday of week 20",
+			body: `DTSTART;TZID=America/New_York:19970512T090000
+RRULE:FREQ=YEARLY;BYWEEKNO=20;BYDAY=MO;COUNT=3`,
+			from: time.Date(1997, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(1997, time.May, 12, 9, 0, 0, 0, ny),
+				time.Date(1998, time.May, 11, 9, 0, 0, 0, ny),
+				time.Date(1999, time.May, 17, 9, 0, 0, 0, ny),
+			},
+		},
+		{
+			name: "yearly on leap days",
+			body: `DTSTART:20200229T100000Z
+RRULE:FREQ=YEARLY;COUNT=2`,
+			from: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2020, time.February, 29, 10, 0, 0, 0, time.UTC),
+				time.Date(2024, time.February, 29, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "hourly with interval",
+			body: `DTSTART:20200101T093000Z
+RRULE:FREQ=HOURLY;INTERVAL=3;UNTIL=20200101T170000Z`,
+			from: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2020, time.January, 1, 9, 30, 0, 0, time.UTC),
+				time.Date(2020, time.January, 1, 12, 30, 0, 0, time.UTC),
+				time.Date(2020, time.January, 1, 15, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "daily with hours & minutes",
+			body: `DTSTART:20200101T090000Z
+RRULE:FREQ=DAILY;BYHOUR=9,17;BYMINUTE=0,30;COUNT=5`,
+			from: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2020, time.January, 1, 9, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 1, 9, 30, 0, 0, time.UTC),
+				time.Date(2020, time.January, 1, 17, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 1, 17, 30, 0, 0, time.UTC),
+				time.Date(2020, time.January, 2, 9, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "count includes a DTSTART that doesn't match the rule",
+			body: `DTSTART:20200101T100000Z
+RRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=3`,
+			from: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2020, time.January, 1, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 6, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 13, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "secondly outside of the hours of the rule",
+			body: `DTSTART:20200101T100000Z
+RRULE:FREQ=SECONDLY;BYHOUR=9;COUNT=3`,
+			from: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2020, time.January, 1, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 2, 9, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 2, 9, 0, 1, 0, time.UTC),
+			},
+		},
+		{
+			name: "hourly on leap days years apart",
+			body: `DTSTART:20970301T000000Z
+RRULE:FREQ=HOURLY;BYMONTH=2;BYMONTHDAY=29;BYHOUR=0;COUNT=2`,
+			from: time.Date(2097, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2105, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2097, time.March, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2104, time.February, 29, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "rule that never matches",
+			body: `DTSTART:20200101T100000Z
+RRULE:FREQ=MINUTELY;BYMONTH=2;BYMONTHDAY=30`,
+			from: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(9999, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2020, time.January, 1, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "multiple RRULEs are merged",
+			body: `DTSTART:20200106T100000Z
+RRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=3
+RRULE:FREQ=WEEKLY;BYDAY=MO,FR;COUNT=3`,
+			from: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2020, time.January, 6, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 10, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 13, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 20, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "EXDATEs & EXRULEs are excluded",
+			body: `DTSTART:20200101T100000Z
+RRULE:FREQ=DAILY;COUNT=10
+EXDATE:20200102T100000Z
+EXDATE:20200104T100000Z,20200105T100000Z
+EXRULE:FREQ=DAILY;INTERVAL=3;COUNT=3`,
+			from: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2020, time.January, 3, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 6, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 8, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 9, 10, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 10, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "EXDATEs in other timezones are compared by instant",
+			body: `DTSTART;TZID=America/New_York:20200101T100000
+RRULE:FREQ=DAILY;COUNT=4
+EXDATE:20200102T150000Z
+EXDATE;TZID=Europe/Berlin:20200103T160000
+EXDATE:20200104T100000Z`,
+			from: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2020, time.January, 1, 10, 0, 0, 0, ny),
+				time.Date(2020, time.January, 4, 10, 0, 0, 0, ny),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := fmt.Sprintf("BEGIN:VCALENDAR\nBEGIN:VEVENT\n%s\nEND:VEVENT\nEND:VCALENDAR", test.body)
+			cal, err := parse.Items(lex.Text(input))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			occs := cal.Events[0].Occurrences(test.from, test.to)
+			assert.Len(t, occs, len(test.expected))
+			for i := range test.expected {
+				if i < len(occs) {
+					assert.True(t, test.expected[i].Equal(occs[i]), "occurrence %d: expected %v; got %v", i, test.expected[i], occs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestItems_recurrenceDates(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+DTSTART:20200101T100000Z
+RRULE:FREQ=DAILY;COUNT=2
+RRULE:FREQ=WEEKLY;COUNT=2
+RDATE:20200105T100000Z
+RDATE:20200107T100000Z,20200108T100000Z
+EXDATE:20200102T100000Z
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evt := cal.Events[0]
+	assert.Len(t, evt.RecurrenceRules, 2)
+	assert.Equal(t, &evt.RecurrenceRules[0], evt.Recurrence)
+	assert.Equal(t, []time.Time{
+		time.Date(2020, time.January, 5, 10, 0, 0, 0, time.UTC),
+		time.Date(2020, time.January, 7, 10, 0, 0, 0, time.UTC),
+		time.Date(2020, time.January, 8, 10, 0, 0, 0, time.UTC),
+	}, evt.RDates)
+	assert.Equal(t, []time.Time{
+		time.Date(2020, time.January, 2, 10, 0, 0, 0, time.UTC),
+	}, evt.ExDates)
+}
+
+func TestEvent_NextOccurrence(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+DTSTART:20200106T100000Z
+RRULE:FREQ=WEEKLY;COUNT=3
+EXDATE:20200113T100000Z
+END:VEVENT
+BEGIN:VEVENT
+DTSTART:20200106T100000Z
+RRULE:FREQ=WEEKLY
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		event    parse.Event
+		after    time.Time
+		expected time.Time
+		ok       bool
+	}{
+		{
+			name:     "before the first occurrence",
+			event:    cal.Events[0],
+			after:    time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2020, time.January, 6, 10, 0, 0, 0, time.UTC),
+			ok:       true,
+		},
+		{
+			name:     "at an occurrence",
+			event:    cal.Events[0],
+			after:    time.Date(2020, time.January, 6, 10, 0, 0, 0, time.UTC),
+			expected: time.Date(2020, time.January, 20, 10, 0, 0, 0, time.UTC),
+			ok:       true,
+		},
+		{
+			name:  "after the last occurrence",
+			event: cal.Events[0],
+			after: time.Date(2020, time.January, 20, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "infinite recurrence",
+			event:    cal.Events[1],
+			after:    time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2030, time.January, 7, 10, 0, 0, 0, time.UTC),
+			ok:       true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			next, ok := test.event.NextOccurrence(test.after)
+			assert.Equal(t, test.ok, ok)
+			assert.True(t, test.expected.Equal(next), "expected %v; got %v", test.expected, next)
+		})
+	}
+}
+
+// cancelAfter is a context that is canceled after its Done method has been
+// called n times, so that tests can cancel at a deterministic point.
+type cancelAfter struct {
+	context.Context
+	cancel context.CancelFunc
+	n      int
+}
+
+func (ctx *cancelAfter) Done() <-chan struct{} {
+	if ctx.n--; ctx.n < 0 {
+		ctx.cancel()
+	}
+	return ctx.Context.Done()
+}
+
+func TestEvent_OccurrencesCtx(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+DTSTART:20200101T100000Z
+RRULE:FREQ=DAILY
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	evt := cal.Events[0]
+
+	from := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2120, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	occs, err := evt.OccurrencesCtx(context.Background(), from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, 36524, len(occs))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	occs, err = evt.OccurrencesCtx(&cancelAfter{Context: ctx, cancel: cancel, n: 100}, from, to)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Len(t, occs, 100)
+	assert.Equal(t, time.Date(2020, time.April, 9, 10, 0, 0, 0, time.UTC), occs[99])
+}
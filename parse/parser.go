@@ -2,9 +2,13 @@ package parse
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -14,6 +18,10 @@ import (
 
 var errEndOfItems = errors.New("end of items")
 
+// ErrDuplicateUID is the error of events that have the same UID and
+// RECURRENCE-ID as a previous event (see DetectDuplicateUIDs).
+var ErrDuplicateUID = errors.New("duplicate UID")
+
 // Error is a parser error.
 type Error struct {
 	Err error
@@ -27,16 +35,65 @@ func (err *Error) Unwrap() error {
 	return err.Err
 }
 
+// Errors are multiple parser errors.
+type Errors []error
+
+func (errs Errors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the errors, so that errors.Is and errors.As
+// match any of the collected errors.
+func (errs Errors) Unwrap() []error {
+	return errs
+}
+
 // Items parses a channel of lex.Item, returns the parsed iCalendar and/or an *Error if it fails.
+// If it fails, the returned Calendar is the partial calendar (see Parser.Parse).
 func Items(items <-chan lex.Item, opts ...Option) (Calendar, error) {
-	p := parser{items: items}
-	for _, opt := range opts {
-		opt(&p)
-	}
-	if p.ctx == nil {
-		p.ctx = context.Background()
+	p := NewParser(opts...)
+	p.Reset(items)
+	return p.Parse()
+}
+
+// ItemsAll parses a channel of lex.Item that contains multiple consecutive
+// calendars, e.g. a feed that concatenates VCALENDAR objects. Every calendar is
+// parsed on its own, so that its properties, components and warnings don't leak
+// into the next one. If a calendar fails to parse, ItemsAll returns the
+// calendars before it, followed by the partial calendar, and the *Error.
+// The HeaderOnly and MaxEvents options stop parsing after the calendar that
+// they stopped.
+func ItemsAll(items <-chan lex.Item, opts ...Option) ([]Calendar, error) {
+	p := NewParser(opts...)
+	p.Reset(items)
+
+	var cals []Calendar
+	for !p.discarded {
+		item, err := p.next()
+		if errors.Is(err, errEndOfItems) {
+			break
+		}
+		if err != nil {
+			return cals, &Error{Err: err}
+		}
+		if item.Type == lex.EOF {
+			break
+		}
+		p.backup()
+
+		p.resetCalendar()
+		cal, err := p.Parse()
+		cals = append(cals, cal)
+		if err != nil {
+			return cals, err
+		}
 	}
-	return p.parse()
+
+	return cals, nil
 }
 
 // Slice parses a slice of lex.Item.
@@ -51,54 +108,364 @@ func Slice(items []lex.Item, opts ...Option) (Calendar, error) {
 	return Items(ch, opts...)
 }
 
+// FromSlice parses a slice of lex.Item like Slice, but reads the items
+// directly from the slice instead of sending them through a channel, so no
+// goroutine is started.
+func FromSlice(items []lex.Item, opts ...Option) (Calendar, error) {
+	p := NewParser(opts...)
+	p.Reset(nil)
+	p.itemSlice = items
+	return p.Parse()
+}
+
 // Option is a parser option.
-type Option func(*parser)
+type Option func(*Parser)
 
 // Context adds a context to the parser.
 func Context(ctx context.Context) Option {
-	return func(p *parser) {
+	return func(p *Parser) {
 		p.ctx = ctx
 	}
 }
 
 // Location configures loc to be used as the *time.Location for parsing
 // date / datetime values that don't explicitly have "UTC" set as the timezone
-// by the "Z" suffix.
+// by the "Z" suffix. loc overrides the TZID parameters of the values; use
+// FloatingLocation to only set the location of floating values.
 func Location(loc *time.Location) Option {
-	return func(p *parser) {
+	return func(p *Parser) {
 		p.loc = loc
 	}
 }
 
+// FloatingLocation configures loc to be used as the *time.Location for parsing
+// floating date / datetime values, which have neither a "Z" suffix nor a
+// resolvable TZID parameter. Unlike Location, values with a TZID parameter keep
+// their timezone. By default, floating values are parsed in the timezone of
+// the X-WR-TIMEZONE calendar property if it is a valid IANA timezone, or else
+// in time.Local, which is the timezone of the server instead of the timezone
+// of the calendar user. FloatingLocation takes precedence over X-WR-TIMEZONE.
+func FloatingLocation(loc *time.Location) Option {
+	return func(p *Parser) {
+		p.floatingLoc = loc
+	}
+}
+
+// NormalizeToUTC configures the parser to convert all parsed date / datetime
+// values to UTC. Floating values (values that neither have a "Z" suffix nor a
+// resolvable TZID parameter and are neither parsed with the Location or
+// FloatingLocation option nor in the X-WR-TIMEZONE) have no timezone that
+// could be converted from, so they are left in local time.
+//
+// Recurrences are expanded in the location of the event's start, so converting
+// the start to UTC makes recurring events ignore daylight saving time transitions.
+func NormalizeToUTC(p *Parser) {
+	p.normalizeToUTC = true
+}
+
+// LenientDates configures the parser to accept date / datetime / duration
+// values that are invalid but emitted by some producers:
+//   - The second 60 (leap second) is clamped to 59.
+//   - Week durations that are combined with days or time (e.g. "P1WT1H") are summed.
+//   - Single-valued date / datetime properties with multiple values
+//     (e.g. "DTSTART:20200101,20200102") are parsed from their first value.
+//   - The TZID parameter of DATE values is applied. By default, DATE values are
+//     floating and their TZID is ignored.
+//   - UTC values (with a "Z" suffix) that also have a TZID parameter are parsed
+//     as UTC and the TZID is ignored. By default, such values are rejected.
+//   - Year-month values (e.g. "202001") are parsed as the DATE of the first
+//     day of the month.
+func LenientDates(p *Parser) {
+	p.lenientDates = true
+}
+
+// LenientGeo configures the parser to accept GEO values whose latitude and
+// longitude are separated by a comma (e.g. "GEO:37.386013,-122.082932") instead
+// of a semicolon, which some producers emit. By default, such values are rejected.
+func LenientGeo(p *Parser) {
+	p.lenientGeo = true
+}
+
+// KeepParamOrder configures the parser to record the order of the parameters
+// of every property in Property.ParamOrder, so that encode.PreserveParamOrder
+// can write them in their original order.
+func KeepParamOrder(p *Parser) {
+	p.keepParamOrder = true
+}
+
 // InclusiveEnds configures the parser to add 1 day to the "End" time field
-// of every event with a DTEND property value of type DATE.
-func InclusiveEnds(p *parser) {
+// of every event with a DTEND property value of type DATE. InclusiveEnds only
+// affects explicit DTEND properties: the implicit one-day duration of all-day
+// events without a DTEND is not extended.
+func InclusiveEnds(p *Parser) {
 	p.inclusiveEnds = true
 }
 
-type parser struct {
-	ctx           context.Context
-	loc           *time.Location
-	inclusiveEnds bool
+// DecodeCalAddress configures the parser to percent-decode the email addresses
+// of "mailto:" calendar user addresses, e.g. "mailto:a%40b.com" results in the
+// Attendee.Email "a@b.com". Addresses that cannot be decoded are left as they are.
+func DecodeCalAddress(p *Parser) {
+	p.decodeCalAddress = true
+}
+
+// CheckLineLength configures the parser to fail on lines that exceed the limit
+// of the lex.MaxOctetsPerLine option. By default, such lines are reported in
+// Calendar.Warnings.
+func CheckLineLength(p *Parser) {
+	p.checkLineLength = true
+}
+
+// KeepProperties configures the parser to discard all event properties from
+// Event.Properties whose names are not listed in names. The typed fields of
+// events are set before the properties are discarded, so they are populated
+// regardless of names. Use KeepProperties to reduce the memory of large
+// calendars when only a few raw properties are needed.
+func KeepProperties(names ...string) Option {
+	return func(p *Parser) {
+		if p.keepProperties == nil {
+			p.keepProperties = make(map[string]bool, len(names))
+		}
+		for _, name := range names {
+			p.keepProperties[name] = true
+		}
+	}
+}
+
+// PartialEvents configures the parser to add the event that failed to parse
+// to the partial calendar that is returned together with the error. The fields
+// of the partial event are set on a best-effort basis. See Parser.Parse for
+// the contract of partial calendars.
+func PartialEvents(p *Parser) {
+	p.partialEvents = true
+}
+
+// Clock configures now as the time source of the parser. The time source is
+// used for values that are derived from the current time, like the DTSTAMP
+// that is added by FillDTSTAMP. Defaults to time.Now.
+func Clock(now func() time.Time) Option {
+	return func(p *Parser) {
+		p.now = now
+	}
+}
+
+// FillDTSTAMP configures the parser to add a DTSTAMP property with the current
+// UTC time (see Clock) to every event that has no DTSTAMP property.
+func FillDTSTAMP(p *Parser) {
+	p.fillDTSTAMP = true
+}
+
+// SkipErrors configures the parser to skip events that cannot be parsed instead
+// of failing. The errors of skipped events are collected in Calendar.Errors.
+func SkipErrors(p *Parser) {
+	p.skipErrors = true
+}
+
+// DetectDuplicateUIDs configures the parser to detect events that have the
+// same UID and RECURRENCE-ID as a previous event of the calendar. An
+// ErrDuplicateUID error is added to Calendar.Errors for every such event.
+// The events are kept in the calendar either way.
+func DetectDuplicateUIDs(p *Parser) {
+	p.detectDuplicateUIDs = true
+}
+
+// HeaderOnly configures the parser to parse only the calendar properties
+// before the first component (e.g. VERSION, PRODID and X-WR-CALNAME) and to
+// stop at the first event or other component, so the returned Calendar has no
+// events and components. The remaining items are received in the background
+// without being parsed; cancel the context of the lexer after parsing to also
+// stop lexing the rest of the input (ical.Parse does this automatically).
+func HeaderOnly(p *Parser) {
+	p.headerOnly = true
+}
+
+// MaxEvents configures the parser to parse at most n events, e.g. to limit the
+// resources used for untrusted feeds. If the calendar has more events, the
+// parser stops at the first event after the limit, sets Calendar.Truncated and
+// returns the calendar without an error. Calendar properties and components
+// after that event are not parsed. The remaining items are received in the
+// background without being parsed (see HeaderOnly). n <= 0 means no limit.
+func MaxEvents(n int) Option {
+	return func(p *Parser) {
+		p.maxEvents = n
+	}
+}
+
+// TransformValue registers fn to transform the value of every property, e.g. to
+// strip HTML or normalize URLs. fn is called with the name and the raw (escaped)
+// value of each property right after it has been lexed, before any typed
+// fields are set. Multiple transforms are applied in the order they are
+// registered. The Raw content line of a property whose value is changed by a
+// transform is cleared.
+func TransformValue(fn func(name, value string) string) Option {
+	return func(p *Parser) {
+		p.transforms = append(p.transforms, fn)
+	}
+}
+
+// FieldMapper registers fn as the handler of event properties with the given
+// name. fn is called for every such property after the event has been parsed
+// and replaces the default handler of the property, if any. A nil fn disables
+// the default handler.
+func FieldMapper(name string, fn func(*Event, Property)) Option {
+	return func(p *Parser) {
+		if p.eventFields == nil {
+			p.eventFields = make(map[string]eventField)
+		}
+
+		if fn == nil {
+			p.eventFields[name] = nil
+			return
+		}
+
+		p.eventFields[name] = func(_ *Parser, evt *Event, prop Property) error {
+			fn(evt, prop)
+			return nil
+		}
+	}
+}
+
+// TZURLFetcher configures the parser to fetch the timezone definitions that the
+// TZURL properties of VTIMEZONE components point to
+// (https://tools.ietf.org/html/rfc5545#section-3.8.3.5). A fetched definition
+// replaces the inline definition of the VTIMEZONE. If fetch fails, the inline
+// definition is used. Fetched definitions are cached by URL for the lifetime
+// of the Parser. By default, TZURL properties are ignored.
+func TZURLFetcher(fetch func(url string) (io.ReadCloser, error)) Option {
+	return func(p *Parser) {
+		p.fetchTZURL = fetch
+	}
+}
+
+// NewParser returns a new Parser that is configured by opts.
+// Call Reset to provide the items before calling Parse.
+func NewParser(opts ...Option) *Parser {
+	var p Parser
+	for _, opt := range opts {
+		opt(&p)
+	}
+	p.setDefaults()
+	return &p
+}
+
+// Parser parses lexed iCalendar items. A Parser can be reused across multiple
+// inputs by calling Reset before each call to Parse, which avoids allocating
+// a new Parser for every file. The zero value is a Parser without options
+// that is ready to use after Reset.
+type Parser struct {
+	ctx                 context.Context
+	loc                 *time.Location
+	floatingLoc         *time.Location
+	inclusiveEnds       bool
+	normalizeToUTC      bool
+	lenientDates        bool
+	lenientGeo          bool
+	keepParamOrder      bool
+	fillDTSTAMP         bool
+	skipErrors          bool
+	decodeCalAddress    bool
+	checkLineLength     bool
+	keepProperties      map[string]bool
+	partialEvents       bool
+	detectDuplicateUIDs bool
+	headerOnly          bool
+	transforms          []func(name, value string) string
+	maxEvents           int
+	now                 func() time.Time
+	eventFields         map[string]eventField
+	fetchTZURL          func(string) (io.ReadCloser, error)
+	tzurlCache          map[string]Component
 
 	items     <-chan lex.Item
+	itemSlice []lex.Item // the items of FromSlice, read instead of items
 	buf       [2]lex.Item
 	start     int
 	pos       int
 	peekCount int
+	discarded bool
+
+	cal      Calendar
+	tzs      map[string]*time.Location
+	warnings []string
+	// calFloatingLoc is the location of the X-WR-TIMEZONE of the calendar
+	calFloatingLoc *time.Location
+}
 
-	cal Calendar
+// Reset discards the state of the previous parse and configures p to parse the given items.
+func (p *Parser) Reset(items <-chan lex.Item) {
+	p.setDefaults()
+	p.items = items
+	p.itemSlice = nil
+	p.buf = [2]lex.Item{}
+	p.start = 0
+	p.pos = 0
+	p.peekCount = 0
+	p.discarded = false
+	p.resetCalendar()
 }
 
-func (p *parser) nextItem() (lex.Item, error) {
-	item, ok := <-p.items
-	if !ok {
-		return item, errEndOfItems
+// setDefaults sets the defaults of the options that have not been configured,
+// so that zero value Parsers can be used.
+func (p *Parser) setDefaults() {
+	if p.ctx == nil {
+		p.ctx = context.Background()
 	}
-	return item, nil
+	if p.now == nil {
+		p.now = time.Now
+	}
+}
+
+// resetCalendar discards the state of the previously parsed calendar.
+func (p *Parser) resetCalendar() {
+	p.cal = Calendar{}
+	p.tzs = nil
+	p.warnings = nil
+	p.calFloatingLoc = nil
 }
 
-func (p *parser) next() (lex.Item, error) {
+// Parse parses the items, returns the parsed iCalendar and/or an *Error if it fails.
+//
+// If parsing fails, the returned Calendar is the partial calendar that has been
+// parsed up to the error: it contains the calendar properties and components
+// and all events before the event that failed to parse. With the PartialEvents
+// option, it also contains the failed event.
+func (p *Parser) Parse() (Calendar, error) {
+	return p.parse()
+}
+
+func (p *Parser) nextItem() (lex.Item, error) {
+	for {
+		item, ok := p.receive()
+		if !ok {
+			return item, errEndOfItems
+		}
+
+		if item.Type != lex.Warning {
+			return item, nil
+		}
+
+		if p.checkLineLength {
+			return item, errors.New(item.Value)
+		}
+		p.warnings = append(p.warnings, item.Value)
+	}
+}
+
+// receive returns the next item of the slice of FromSlice or of the items channel.
+func (p *Parser) receive() (lex.Item, bool) {
+	if p.items != nil {
+		item, ok := <-p.items
+		return item, ok
+	}
+
+	if len(p.itemSlice) == 0 {
+		return lex.Item{}, false
+	}
+	item := p.itemSlice[0]
+	p.itemSlice = p.itemSlice[1:]
+	return item, true
+}
+
+func (p *Parser) next() (lex.Item, error) {
 	select {
 	case <-p.ctx.Done():
 		return lex.Item{}, p.ctx.Err()
@@ -116,7 +483,20 @@ func (p *parser) next() (lex.Item, error) {
 	return p.buf[p.peekCount], nil
 }
 
-func (p *parser) nextType(typ lex.ItemType) (lex.Item, error) {
+// discard receives the remaining items in the background, so that the lexer
+// that sends them doesn't block forever.
+func (p *Parser) discard() {
+	p.discarded = true
+	if p.items == nil {
+		return
+	}
+	go func(items <-chan lex.Item) {
+		for range items {
+		}
+	}(p.items)
+}
+
+func (p *Parser) nextType(typ lex.ItemType) (lex.Item, error) {
 	item, err := p.next()
 	if err != nil {
 		return item, err
@@ -129,7 +509,7 @@ func (p *parser) nextType(typ lex.ItemType) (lex.Item, error) {
 	return item, nil
 }
 
-func (p *parser) peek() (lex.Item, error) {
+func (p *Parser) peek() (lex.Item, error) {
 	if p.peekCount > 0 {
 		return p.buf[p.peekCount-1], nil
 	}
@@ -141,26 +521,26 @@ func (p *parser) peek() (lex.Item, error) {
 	return p.buf[0], nil
 }
 
-func (p *parser) backup() {
+func (p *Parser) backup() {
 	p.peekCount++
 }
 
-func (p *parser) errorf(format string, vals ...interface{}) error {
+func (p *Parser) errorf(format string, vals ...interface{}) error {
 	return fmt.Errorf(format, vals...)
 }
 
-func (p *parser) unexpectedType(item lex.Item, expected lex.ItemType) error {
+func (p *Parser) unexpectedType(item lex.Item, expected lex.ItemType) error {
 	return p.errorf("expected item of type %v; got %s", expected, item)
 }
 
-func (p *parser) parse() (Calendar, error) {
+func (p *Parser) parse() (Calendar, error) {
 	if err := p.parseCalendar(); err != nil {
 		return p.cal, &Error{Err: err}
 	}
 	return p.cal, nil
 }
 
-func (p *parser) parseCalendar() error {
+func (p *Parser) parseCalendar() error {
 	item, err := p.next()
 	if err != nil {
 		return err
@@ -174,6 +554,107 @@ func (p *parser) parseCalendar() error {
 		Calscale: "GREGORIAN",
 	}
 
+	// events are lifted after all components have been parsed,
+	// because they may reference VTIMEZONEs that are defined after them
+	var events []Event
+	var partial *Event
+
+	err = p.parseCalendarItems(&cal, &events, &partial)
+
+	// calendar properties are lifted after all items have been parsed,
+	// so they may also appear after the components of the calendar
+	for _, prop := range cal.Properties {
+		switch prop.Name {
+		case "VERSION":
+			cal.Version = prop.Value
+		case "METHOD":
+			cal.Method = prop.Value
+		case "PRODID":
+			cal.ProductID = prop.Value
+		case "CALSCALE":
+			cal.Calscale = prop.Value
+		case "X-WR-RELCALID":
+			cal.RelatedCalendarID = prop.Value
+		case "X-WR-TIMEZONE":
+			// feeds that rely on X-WR-TIMEZONE have floating times in that timezone
+			if loc, err := time.LoadLocation(prop.Value); err == nil && prop.Value != "" {
+				p.calFloatingLoc = loc
+			}
+		}
+	}
+
+	cal.Timezones = p.timezones(cal.Components)
+	p.tzs = cal.Timezones
+	cal.Warnings = p.warnings
+
+	for _, evt := range events {
+		if liftErr := p.liftEvent(&evt, false); liftErr != nil {
+			if !p.skipErrors {
+				if err == nil {
+					err = liftErr
+				}
+				// the events after a failed event are not part of the partial calendar
+				break
+			}
+			cal.Errors = append(cal.Errors, &Error{Err: liftErr})
+			continue
+		}
+		cal.Events = append(cal.Events, evt)
+	}
+
+	if p.detectDuplicateUIDs {
+		cal.Errors = append(cal.Errors, duplicateUIDs(cal.Events)...)
+	}
+
+	if partial != nil && err != nil {
+		// the partial event is lifted on a best-effort basis
+		p.liftEvent(partial, false)
+		cal.Events = append(cal.Events, *partial)
+	}
+
+	p.cal = cal
+
+	return err
+}
+
+// duplicateUIDs returns an ErrDuplicateUID error for every event that has the
+// same UID and RECURRENCE-ID as a previous event.
+func duplicateUIDs(events []Event) Errors {
+	type key struct {
+		uid          string
+		recurrenceID int64
+	}
+
+	var errs Errors
+	seen := make(map[key]bool, len(events))
+	for _, evt := range events {
+		if evt.UID == "" {
+			continue
+		}
+
+		k := key{uid: evt.UID}
+		if !evt.RecurrenceID.IsZero() {
+			k.recurrenceID = evt.RecurrenceID.UnixNano()
+		}
+
+		if seen[k] {
+			errs = append(errs, &Error{Err: fmt.Errorf("%w %q", ErrDuplicateUID, evt.UID)})
+			continue
+		}
+		seen[k] = true
+	}
+
+	return errs
+}
+
+// parseCalendarItems parses the properties and components of a calendar into cal.
+// The events are collected in events without lifting their fields. If an event
+// fails to parse and the PartialEvents option is set, the partially parsed
+// event is returned in partial.
+func (p *Parser) parseCalendarItems(cal *Calendar, events *[]Event, partial **Event) error {
+	var item lex.Item
+	var err error
+
 loop:
 	for {
 		item, err = p.next()
@@ -181,16 +662,37 @@ loop:
 			return err
 		}
 
+		if p.headerOnly && (item.Type == lex.EventBegin || item.Type == lex.ComponentBegin) {
+			p.discard()
+			return nil
+		}
+
+		if item.Type == lex.EventBegin && p.maxEvents > 0 && len(*events) >= p.maxEvents {
+			cal.Truncated = true
+			p.discard()
+			return nil
+		}
+
 		switch item.Type {
 		case lex.CalendarEnd:
 			break loop
 		case lex.EventBegin:
 			p.backup()
 			evt, err := p.parseEvent()
+			if err != nil {
+				if p.partialEvents {
+					*partial = &evt
+				}
+				return err
+			}
+			*events = append(*events, evt)
+		case lex.ComponentBegin:
+			p.backup()
+			comp, err := p.parseComponent()
 			if err != nil {
 				return err
 			}
-			cal.Events = append(cal.Events, evt)
+			cal.Components = append(cal.Components, comp)
 		case lex.Name:
 			p.backup()
 			prop, err := p.parseProperty()
@@ -207,23 +709,10 @@ loop:
 		return p.unexpectedType(item, lex.CalendarEnd)
 	}
 
-	for _, prop := range cal.Properties {
-		switch prop.Name {
-		case "VERSION":
-			cal.Version = prop.Value
-		case "METHOD":
-			cal.Method = prop.Value
-		case "PRODID":
-			cal.ProductID = prop.Value
-		}
-	}
-
-	p.cal = cal
-
 	return nil
 }
 
-func (p *parser) parseEvent() (Event, error) {
+func (p *Parser) parseEvent() (Event, error) {
 	var evt Event
 	item, err := p.nextType(lex.EventBegin)
 	if err != nil {
@@ -249,6 +738,14 @@ loop:
 			}
 			evt.Alarms = append(evt.Alarms, alarm)
 			continue
+		case lex.ComponentBegin:
+			p.backup()
+			comp, err := p.parseComponent()
+			if err != nil {
+				return evt, err
+			}
+			evt.Components = append(evt.Components, comp)
+			continue
 		default:
 		}
 
@@ -268,43 +765,73 @@ loop:
 		return evt, err
 	}
 
+	return evt, nil
+}
+
+// liftEvent sets the fields of evt from its raw properties. If skipInvalid is
+// true, properties whose values cannot be parsed are skipped instead of
+// returning the first error.
+func (p *Parser) liftEvent(evt *Event, skipInvalid bool) error {
+	if _, ok := evt.Property("DTSTAMP"); !ok && p.fillDTSTAMP {
+		evt.Properties = append(evt.Properties, Property{
+			Name:   "DTSTAMP",
+			Params: make(Parameters),
+			Value:  p.now().UTC().Format(layoutDateTimeUTC),
+		})
+	}
+
 	for _, prop := range evt.Properties {
-		switch prop.Name {
-		case "UID":
-			evt.UID = prop.Value
-		case "DTSTART":
-			t, err := p.parseTime(prop)
-			if err != nil {
-				return evt, err
-			}
-			evt.Start = t
-		case "DTEND":
-			t, err := p.parseDTEND(prop)
-			if err != nil {
-				return evt, err
-			}
-			evt.End = t
-		case "DTSTAMP":
-			t, err := p.parseTime(prop)
-			if err != nil {
-				return evt, err
-			}
-			evt.Timestamp = t
-		case "SUMMARY":
-			evt.Summary = prop.Value
-		case "DESCRIPTION":
-			evt.Description = prop.Value
+		field, ok := p.eventFields[prop.Name]
+		if !ok {
+			field = defaultEventFields[prop.Name]
+		}
+
+		if field == nil {
+			continue
+		}
+
+		if err := field(p, evt, prop); err != nil && !skipInvalid {
+			return err
 		}
 	}
 
-	if err := evt.finalize(); err != nil {
-		return evt, err
+	if err := evt.finalize(p.lenientDates); err != nil && !skipInvalid {
+		return err
 	}
 
-	return evt, nil
+	if p.keepProperties != nil {
+		evt.Properties = p.keptProperties(evt.Properties)
+	}
+
+	return nil
+}
+
+// keptProperties returns a copy of the properties whose names are configured by
+// the KeepProperties option. The properties are copied, so that the discarded
+// properties can be garbage collected.
+func (p *Parser) keptProperties(props []Property) []Property {
+	var n int
+	for _, prop := range props {
+		if p.keepProperties[prop.Name] {
+			n++
+		}
+	}
+
+	kept := make([]Property, 0, n)
+	for _, prop := range props {
+		if p.keepProperties[prop.Name] {
+			kept = append(kept, prop)
+		}
+	}
+	return kept
 }
 
-func (p *parser) parseAlarm() (Alarm, error) {
+// maxAlarmRepeat is the largest REPEAT value of an alarm. Larger values are
+// treated as invalid, so that Alarm.FireTimes cannot be made to allocate
+// an arbitrary number of times.
+const maxAlarmRepeat = 1000
+
+func (p *Parser) parseAlarm() (Alarm, error) {
 	var alarm Alarm
 
 	item, err := p.nextType(lex.AlarmBegin)
@@ -323,6 +850,16 @@ func (p *parser) parseAlarm() (Alarm, error) {
 			break
 		}
 
+		if item.Type == lex.ComponentBegin {
+			p.backup()
+			comp, err := p.parseComponent()
+			if err != nil {
+				return alarm, err
+			}
+			alarm.Components = append(alarm.Components, comp)
+			continue
+		}
+
 		if item.Type != lex.Name {
 			return alarm, p.unexpectedType(item, lex.Name)
 		}
@@ -342,16 +879,251 @@ func (p *parser) parseAlarm() (Alarm, error) {
 	for _, prop := range alarm.Properties {
 		switch prop.Name {
 		case "TRIGGER":
-			alarm.Trigger = prop.Value
+			alarm.Trigger = p.parseTrigger(prop)
 		case "ACTION":
 			alarm.Action = prop.Value
+		case "PROXIMITY":
+			alarm.Proximity = strings.ToUpper(prop.Value)
+		case "ACKNOWLEDGED":
+			// an invalid value leaves Acknowledged zero; the raw property is kept
+			if t, err := p.parseTime(prop); err == nil {
+				alarm.Acknowledged = t
+			}
+		case "REPEAT":
+			// an invalid value leaves Repeat zero; the raw property is kept
+			if n, err := strconv.Atoi(prop.Value); err == nil && n >= 0 && n <= maxAlarmRepeat {
+				alarm.Repeat = n
+			}
+		case "DURATION":
+			if d, err := parseDuration(prop.Value, p.lenientDates); err == nil {
+				alarm.Duration = d
+			}
 		}
 	}
 
 	return alarm, nil
 }
 
-func (p *parser) parseProperty() (Property, error) {
+// parseTrigger parses the absolute (VALUE=DATE-TIME) or relative (VALUE=DURATION)
+// TRIGGER prop of an alarm. Values that don't start with "P", "+P" or "-P" are
+// parsed as DATE-TIME values even without a VALUE parameter. A value that is
+// neither a valid DATE-TIME nor a valid duration results in a zero Trigger.
+func (p *Parser) parseTrigger(prop Property) Trigger {
+	if prop.ValueType() == "DATE-TIME" || !isDurationValue(prop.Value) {
+		if t, err := p.parseTime(prop); err == nil {
+			return Trigger{Time: t}
+		}
+	}
+
+	d, err := parseDuration(prop.Value, p.lenientDates)
+	if err != nil {
+		return Trigger{}
+	}
+
+	related := "START"
+	if rel, ok := prop.Params.First("RELATED"); ok {
+		related = strings.ToUpper(rel)
+	}
+
+	return Trigger{Duration: d, Related: related}
+}
+
+// isDurationValue determines if v looks like a duration, i.e. starts with
+// "P", "+P" or "-P".
+func isDurationValue(v string) bool {
+	if strings.HasPrefix(v, "+") || strings.HasPrefix(v, "-") {
+		v = v[1:]
+	}
+	return strings.HasPrefix(v, "P")
+}
+
+// parseComponent parses a component that has no dedicated type.
+func (p *Parser) parseComponent() (Component, error) {
+	var comp Component
+
+	item, err := p.next()
+	if err != nil {
+		return comp, err
+	}
+
+	var endType lex.ItemType
+	switch item.Type {
+	case lex.ComponentBegin:
+		endType = lex.ComponentEnd
+	case lex.AlarmBegin:
+		endType = lex.AlarmEnd
+	default:
+		return comp, p.unexpectedType(item, lex.ComponentBegin)
+	}
+	comp.Name = strings.TrimPrefix(item.Value, "BEGIN:")
+
+	for {
+		item, err = p.next()
+		if err != nil {
+			return comp, err
+		}
+
+		switch item.Type {
+		case endType:
+			if name := strings.TrimPrefix(item.Value, "END:"); name != comp.Name {
+				return comp, p.errorf("expected END:%s; got END:%s", comp.Name, name)
+			}
+			return comp, nil
+		case lex.ComponentBegin, lex.AlarmBegin:
+			p.backup()
+			sub, err := p.parseComponent()
+			if err != nil {
+				return comp, fmt.Errorf("%s: %w", comp.Name, err)
+			}
+			comp.Components = append(comp.Components, sub)
+		case lex.Name:
+			p.backup()
+			prop, err := p.parseProperty()
+			if err != nil {
+				return comp, err
+			}
+			comp.Properties = append(comp.Properties, prop)
+		default:
+			return comp, p.unexpectedType(item, endType)
+		}
+	}
+}
+
+func parseConference(prop Property) Conference {
+	conf := Conference{URI: prop.Value}
+	conf.Label, _ = prop.Params.First("LABEL")
+	for _, feature := range prop.Params["FEATURE"] {
+		conf.Features = append(conf.Features, unquote(feature))
+	}
+	return conf
+}
+
+// parseAttachment parses an ATTACH prop. An invalid SIZE parameter leaves Size
+// at 0 and an invalid BASE64 value leaves Data nil; the raw property is kept.
+func parseAttachment(prop Property) Attachment {
+	var att Attachment
+	att.FormatType, _ = prop.Params.First("FMTTYPE")
+	att.Filename, _ = prop.Params.First("FILENAME")
+	att.ManagedID, _ = prop.Params.First("MANAGED-ID")
+
+	if size, ok := prop.Params.First("SIZE"); ok {
+		if n, err := strconv.ParseInt(size, 10, 64); err == nil && n >= 0 {
+			att.Size = n
+		}
+	}
+
+	if enc, _ := prop.Params.First("ENCODING"); strings.EqualFold(enc, "BASE64") {
+		if data, err := base64.StdEncoding.DecodeString(prop.Value); err == nil {
+			att.Data = data
+		}
+		return att
+	}
+
+	att.URI = prop.Value
+
+	return att
+}
+
+func parseRequestStatus(prop Property) RequestStatus {
+	parts := splitEscaped(prop.Value, ';')
+	status := RequestStatus{Code: parts[0]}
+	if len(parts) > 1 {
+		status.Description = unescapeText(parts[1])
+	}
+	if len(parts) > 2 {
+		status.ExtraData = unescapeText(strings.Join(parts[2:], ";"))
+	}
+	return status
+}
+
+// geo = geo-value ";" geo-value
+func (p *Parser) parseGeo(prop Property) (GeoPoint, error) {
+	sep := ";"
+	if p.lenientGeo && !strings.Contains(prop.Value, sep) {
+		sep = ","
+	}
+
+	parts := strings.Split(prop.Value, sep)
+	if len(parts) != 2 {
+		return GeoPoint{}, fmt.Errorf("invalid value %q; expected latitude and longitude separated by \";\"", prop.Value)
+	}
+
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return GeoPoint{}, fmt.Errorf("invalid latitude %q", parts[0])
+	}
+
+	lon, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return GeoPoint{}, fmt.Errorf("invalid longitude %q", parts[1])
+	}
+
+	return GeoPoint{Latitude: lat, Longitude: lon}, nil
+}
+
+func (p *Parser) parseAttendee(prop Property) Attendee {
+	att := Attendee{
+		Address:        prop.Value,
+		Email:          p.calAddressEmail(prop.Value),
+		ScheduleAgent:  scheduleAgent(prop),
+		ScheduleStatus: prop.Params.Values("SCHEDULE-STATUS"),
+	}
+
+	att.CommonName, _ = prop.Params.First("CN")
+	att.Role, _ = prop.Params.First("ROLE")
+	att.PartStat, _ = prop.Params.First("PARTSTAT")
+
+	if rsvp, ok := prop.Params.First("RSVP"); ok {
+		switch strings.ToUpper(rsvp) {
+		case "TRUE":
+			att.RSVP = boolPtr(true)
+		case "FALSE":
+			att.RSVP = boolPtr(false)
+		}
+	}
+
+	return att
+}
+
+func (p *Parser) parseOrganizer(prop Property) Organizer {
+	org := Organizer{
+		Address:        prop.Value,
+		Email:          p.calAddressEmail(prop.Value),
+		ScheduleAgent:  scheduleAgent(prop),
+		ScheduleStatus: prop.Params.Values("SCHEDULE-STATUS"),
+	}
+	org.CommonName, _ = prop.Params.First("CN")
+	return org
+}
+
+// calAddressEmail returns the email address of a "mailto:" calendar user
+// address, or an empty string if addr is not a "mailto:" address.
+func (p *Parser) calAddressEmail(addr string) string {
+	email := calAddressEmail(addr)
+	if email == addr {
+		return ""
+	}
+
+	if p.decodeCalAddress {
+		if decoded, err := url.PathUnescape(email); err == nil {
+			return decoded
+		}
+	}
+
+	return email
+}
+
+// scheduleAgent returns the upper-cased SCHEDULE-AGENT parameter of prop.
+func scheduleAgent(prop Property) string {
+	agent, _ := prop.Params.First("SCHEDULE-AGENT")
+	return strings.ToUpper(agent)
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func (p *Parser) parseProperty() (Property, error) {
 	var name string
 	params := make(Parameters)
 
@@ -361,13 +1133,19 @@ func (p *parser) parseProperty() (Property, error) {
 	}
 	name = item.Value
 
+	// e.g. "END;X-FOO=bar:VEVENT", which would be encoded as the end of a component
+	if name == "BEGIN" || name == "END" {
+		return Property{}, p.errorf("%s line must not have parameters", name)
+	}
+
 	if item, err = p.next(); err != nil {
 		return Property{}, err
 	}
 
+	var order []string
 	if item.Type == lex.ParamName {
 		p.backup()
-		if err = p.parseParams(params); err != nil {
+		if order, err = p.parseParams(params); err != nil {
 			return Property{}, err
 		}
 		if item, err = p.nextType(lex.Value); err != nil {
@@ -379,18 +1157,34 @@ func (p *parser) parseProperty() (Property, error) {
 		return Property{}, p.unexpectedType(item, lex.Value)
 	}
 
-	return Property{
+	prop := Property{
 		Name:   name,
 		Params: params,
 		Value:  item.Value,
-	}, nil
+		Raw:    item.Raw,
+	}
+	if p.keepParamOrder {
+		prop.ParamOrder = order
+	}
+
+	for _, transform := range p.transforms {
+		prop.Value = transform(prop.Name, prop.Value)
+	}
+	if prop.Value != item.Value {
+		prop.Raw = ""
+	}
+
+	return prop, nil
 }
 
-func (p *parser) parseParams(params Parameters) error {
+// parseParams parses the parameters of a property into params and returns
+// their names in the order of the input.
+func (p *Parser) parseParams(params Parameters) ([]string, error) {
+	var order []string
 	for {
 		item, err := p.next()
 		if err != nil {
-			return err
+			return order, err
 		}
 
 		if item.Type != lex.ParamName {
@@ -404,7 +1198,7 @@ func (p *parser) parseParams(params Parameters) error {
 		for {
 			item, err = p.next()
 			if err != nil {
-				return err
+				return order, err
 			}
 
 			if item.Type != lex.ParamValue {
@@ -415,10 +1209,13 @@ func (p *parser) parseParams(params Parameters) error {
 			values = append(values, item.Value)
 		}
 
+		if _, ok := params[name]; !ok {
+			order = append(order, name)
+		}
 		params[name] = values
 	}
 
-	return nil
+	return order, nil
 }
 
 const (
@@ -427,8 +1224,8 @@ const (
 	layoutDateTimeLocal = "20060102T150405"
 )
 
-func (p *parser) parseDTEND(prop Property) (time.Time, error) {
-	if len(prop.Value) != len(layoutDate) {
+func (p *Parser) parseDTEND(prop Property) (time.Time, error) {
+	if !isDateValue(prop) {
 		return p.parseTime(prop)
 	}
 
@@ -440,35 +1237,168 @@ func (p *parser) parseDTEND(prop Property) (time.Time, error) {
 	return t.AddDate(0, 0, 1), nil
 }
 
-func (p *parser) parseTime(prop Property) (time.Time, error) {
+// NewEvent returns an Event with a property for every entry of props, e.g.
+// {"DTSTART": "20200101T100000Z", "DURATION": "PT1H"}, whose typed fields are
+// set like the fields of parsed events. The properties are sorted by name and
+// have no parameters. Values that cannot be parsed are ignored; use
+// EventFromProperties for properties with parameters and to get the errors.
+func NewEvent(props map[string]string, opts ...Option) Event {
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	eventProps := make([]Property, len(names))
+	for i, name := range names {
+		eventProps[i] = Property{
+			Name:   strings.ToUpper(name),
+			Params: make(Parameters),
+			Value:  props[name],
+		}
+	}
+
+	evt := Event{Properties: eventProps}
+	NewParser(opts...).liftEvent(&evt, true)
+	return evt
+}
+
+// EventFromProperties returns an Event with the given properties, whose typed
+// fields are set like the fields of parsed events, using the parser for the
+// given options. TZID parameters are resolved with time.LoadLocation.
+func EventFromProperties(props []Property, opts ...Option) (Event, error) {
+	p := NewParser(opts...)
+	evt := Event{Properties: append([]Property(nil), props...)}
+	if err := p.liftEvent(&evt, false); err != nil {
+		return evt, &Error{Err: err}
+	}
+	return evt, nil
+}
+
+// Time parses the DATE or DATE-TIME value of prop, using the same rules as the
+// parser for the given options, e.g. the Location and LenientDates options.
+// A TZID parameter is resolved with time.LoadLocation.
+func Time(prop Property, opts ...Option) (time.Time, error) {
+	return NewParser(opts...).parseTime(prop)
+}
+
+// Duration parses the DURATION value val (https://tools.ietf.org/html/rfc5545#section-3.3.6),
+// e.g. "PT1H30M" or "-P1D".
+func Duration(val string) (time.Duration, error) {
+	return parseDuration(val, false)
+}
+
+func (p *Parser) parseTime(prop Property) (time.Time, error) {
+	if i := strings.IndexByte(prop.Value, ','); i >= 0 {
+		if !p.lenientDates {
+			return time.Time{}, fmt.Errorf("%s must have a single value; got %q", prop.Name, prop.Value)
+		}
+		prop.Value = prop.Value[:i]
+	}
+
 	prop.Value = normalizeDateTimeValue(prop.Value)
 
+	if p.lenientDates {
+		prop.Value = clampLeapSecond(prop.Value)
+		if isYearMonth(prop.Value) {
+			prop.Value += "01"
+		}
+	}
+
 	var layout string
 	loc := time.Local
+	floating := true
 
 	if strings.HasSuffix(prop.Value, "Z") {
+		if tzid, ok := prop.Params.First("TZID"); ok && !p.lenientDates {
+			return time.Time{}, fmt.Errorf("%s: TZID %q conflicts with UTC value %q", prop.Name, tzid, prop.Value)
+		}
 		layout = layoutDateTimeUTC
 		loc = time.UTC
+		floating = false
 	} else {
 		layout = parseLayout(prop)
+		if layout == layoutDate && len(prop.Value) != len(layout) {
+			layout = layoutDateTimeLocal
+		}
+
+		// DATE values are floating, so their TZID is ignored unless lenient
+		ignoreTZID := layout == layoutDate && !p.lenientDates
 
 		if p.loc != nil {
 			loc = p.loc
-		} else if tzRaw, ok := prop.Params["TZID"]; ok {
+			floating = false
+		} else if tzRaw, ok := prop.Params["TZID"]; ok && !ignoreTZID {
 			for _, raw := range tzRaw {
-				if tzloc, err := time.LoadLocation(raw); err == nil {
+				if tzloc, ok := p.location(unquote(raw)); ok {
 					loc = tzloc
+					floating = false
 					break
 				}
 			}
 		}
+
+		if floating && p.floatingLoc != nil {
+			loc = p.floatingLoc
+			floating = false
+		} else if floating && p.calFloatingLoc != nil {
+			loc = p.calFloatingLoc
+			floating = false
+		}
+	}
+
+	t, err := time.ParseInLocation(layout, prop.Value, loc)
+	if err != nil || floating || !p.normalizeToUTC {
+		return t, err
+	}
+
+	return t.UTC(), nil
+}
+
+// clampLeapSecond replaces the second 60 of a datetime value with 59.
+func clampLeapSecond(val string) string {
+	if len(val) >= len(layoutDateTimeLocal) && val[8] == 'T' && val[13:15] == "60" {
+		return val[:13] + "59" + val[15:]
 	}
+	return val
+}
 
-	if layout == layoutDate && len(prop.Value) != len(layout) {
-		layout = layoutDateTimeLocal
+// isYearMonth determines if val is a year-month value (e.g. "202001").
+func isYearMonth(val string) bool {
+	if len(val) != len("200601") {
+		return false
+	}
+	for _, c := range val {
+		if c < '0' || c > '9' {
+			return false
+		}
 	}
+	return true
+}
+
+// parseTimeList parses the comma-separated date / datetime values of prop.
+// For PERIOD values, only the start of each period is returned.
+func (p *Parser) parseTimeList(prop Property) ([]time.Time, error) {
+	vals := strings.Split(prop.Value, ",")
+	times := make([]time.Time, 0, len(vals))
+	for _, val := range vals {
+		if prop.ValueType() == "PERIOD" {
+			if i := strings.IndexByte(val, '/'); i >= 0 {
+				val = val[:i]
+			}
+		}
 
-	return time.ParseInLocation(layout, prop.Value, loc)
+		t, err := p.parseTime(Property{
+			Name:   prop.Name,
+			Params: prop.Params,
+			Value:  val,
+		})
+		if err != nil {
+			return times, err
+		}
+		times = append(times, t)
+	}
+	return times, nil
 }
 
 func parseLayout(prop Property) string {
@@ -480,10 +1410,10 @@ func parseLayout(prop Property) string {
 		}
 
 		for _, val := range values {
-			switch val {
+			switch strings.ToUpper(val) {
 			case "DATE-TIME":
 				layout = layoutDateTimeLocal
-			default:
+			case "DATE":
 				layout = layoutDate
 			}
 		}
@@ -509,6 +1439,15 @@ var (
 	shortTimeLayoutRE = regexp.MustCompile(`([0-9]+T)([0-9]{3,5})(Z?)$`)
 )
 
+// normalizeDateTimeValue pads the time of DATE-TIME values that have fewer than
+// 6 digits, which are emitted by some producers. The digits are read from the
+// right, so that the minutes (and seconds) always have 2 digits:
+//   - 3 digits: HMM (e.g. "930" is 09:30:00)
+//   - 4 digits: HHMM (e.g. "1358" is 13:58:00)
+//   - 5 digits: HMMSS (e.g. "93015" is 09:30:15)
+//
+// Values whose components are out of range (e.g. "2560") are not corrected
+// and fail to parse.
 func normalizeDateTimeValue(val string) string {
 	return replaceAllStringSubmatchFunc(shortTimeLayoutRE, val, func(groups []string) string {
 		if len(groups) < 4 {
@@ -517,12 +1456,12 @@ func normalizeDateTimeValue(val string) string {
 
 		timeVal := groups[2]
 		switch len(timeVal) {
-		case 3: // Hms
-			timeVal = fmt.Sprintf("0%s0%s0%s", string(timeVal[0]), string(timeVal[1]), string(timeVal[2]))
-		case 4: // HHms | Hmms
-			timeVal = normalizeTimeValue(timeVal, 4)
-		case 5: // HHmms
-			timeVal = normalizeTimeValue(timeVal, 5)
+		case 3: // HMM
+			timeVal = "0" + timeVal + "00"
+		case 4: // HHMM
+			timeVal += "00"
+		case 5: // HMMSS
+			timeVal = "0" + timeVal
 		default:
 			return val
 		}
@@ -544,50 +1483,3 @@ func replaceAllStringSubmatchFunc(re *regexp.Regexp, str string, repl func([]str
 	}
 	return result + str[lastIndex:]
 }
-
-var (
-	now = time.Now()
-)
-
-func normalizeTimeValue(val string, digits int) string {
-	var hour, minute, second, offset, foundCount int
-	found := func() bool { return foundCount >= (digits - 3) }
-
-	if hour, _ = strconv.Atoi(val[offset : offset+2]); hour < 24 {
-		foundCount++
-		offset += 2
-	} else {
-		hour, _ = strconv.Atoi(val[offset : offset+1])
-		offset++
-	}
-
-	if !found() {
-		if minute, _ = strconv.Atoi(val[offset : offset+2]); minute < 60 {
-			foundCount++
-			offset += 2
-		} else {
-			minute, _ = strconv.Atoi(val[offset : offset+1])
-			offset++
-		}
-	} else {
-		minute, _ = strconv.Atoi(val[offset : offset+1])
-		offset++
-	}
-
-	if !found() {
-		if second, _ = strconv.Atoi(val[offset : offset+2]); second < 60 {
-			foundCount++
-			offset += 2
-		} else {
-			second, _ = strconv.Atoi(val[offset : offset+1])
-			offset++
-		}
-	} else {
-		second, _ = strconv.Atoi(val[offset : offset+1])
-		offset++
-	}
-
-	return time.
-		Date(now.Year(), now.Month(), now.Day(), hour, minute, second, 0, time.UTC).
-		Format("150405")
-}
@@ -2,7 +2,10 @@ package parse_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -150,7 +153,7 @@ func TestItems_timeParsing(t *testing.T) {
 				assert.Equal(t, time.Date(2020, time.January, 1, 10, 30, 20, 0, time.UTC).Unix(), cal.Events[0].Timestamp.Unix())
 			},
 		},
-		"DATE-TIME (4-digit time / 2-digit hour (local))": {
+		"DATE-TIME (4-digit time HHMM (local))": {
 			items: []lex.Item{
 				testutil.BeginCalendar(),
 				testutil.BeginEvent(),
@@ -162,10 +165,10 @@ func TestItems_timeParsing(t *testing.T) {
 				testutil.EndCalendar(),
 			},
 			expect: func(t *testing.T, cal parse.Calendar) {
-				assert.Equal(t, time.Date(2020, time.January, 1, 13, 5, 8, 0, time.Local).Unix(), cal.Events[0].Timestamp.Unix())
+				assert.Equal(t, time.Date(2020, time.January, 1, 13, 58, 0, 0, time.Local).Unix(), cal.Events[0].Timestamp.Unix())
 			},
 		},
-		"DATE-TIME (4-digit time / 2-digit hour (UTC))": {
+		"DATE-TIME (4-digit time HHMM (UTC))": {
 			items: []lex.Item{
 				testutil.BeginCalendar(),
 				testutil.BeginEvent(),
@@ -177,40 +180,10 @@ func TestItems_timeParsing(t *testing.T) {
 				testutil.EndCalendar(),
 			},
 			expect: func(t *testing.T, cal parse.Calendar) {
-				assert.Equal(t, time.Date(2020, time.January, 1, 13, 5, 8, 0, time.UTC).Unix(), cal.Events[0].Timestamp.Unix())
+				assert.Equal(t, time.Date(2020, time.January, 1, 13, 58, 0, 0, time.UTC).Unix(), cal.Events[0].Timestamp.Unix())
 			},
 		},
-		"DATE-TIME (4-digit time / 2-digit minute (local))": {
-			items: []lex.Item{
-				testutil.BeginCalendar(),
-				testutil.BeginEvent(),
-				testutil.Item(lex.Name, "DTSTAMP"),
-				testutil.Item(lex.ParamName, "VALUE"),
-				testutil.Item(lex.ParamValue, "DATE-TIME"),
-				testutil.Item(lex.Value, "20200101T3158"),
-				testutil.EndEvent(),
-				testutil.EndCalendar(),
-			},
-			expect: func(t *testing.T, cal parse.Calendar) {
-				assert.Equal(t, time.Date(2020, time.January, 1, 3, 15, 8, 0, time.Local).Unix(), cal.Events[0].Timestamp.Unix())
-			},
-		},
-		"DATE-TIME (4-digit time / 2-digit minute (UTC))": {
-			items: []lex.Item{
-				testutil.BeginCalendar(),
-				testutil.BeginEvent(),
-				testutil.Item(lex.Name, "DTSTAMP"),
-				testutil.Item(lex.ParamName, "VALUE"),
-				testutil.Item(lex.ParamValue, "DATE-TIME"),
-				testutil.Item(lex.Value, "20200101T3158Z"),
-				testutil.EndEvent(),
-				testutil.EndCalendar(),
-			},
-			expect: func(t *testing.T, cal parse.Calendar) {
-				assert.Equal(t, time.Date(2020, time.January, 1, 3, 15, 8, 0, time.UTC).Unix(), cal.Events[0].Timestamp.Unix())
-			},
-		},
-		"DATE-TIME (5-digit time / 2-digit hour (local))": {
+		"DATE-TIME (5-digit time HMMSS (local))": {
 			items: []lex.Item{
 				testutil.BeginCalendar(),
 				testutil.BeginEvent(),
@@ -222,10 +195,10 @@ func TestItems_timeParsing(t *testing.T) {
 				testutil.EndCalendar(),
 			},
 			expect: func(t *testing.T, cal parse.Calendar) {
-				assert.Equal(t, time.Date(2020, time.January, 1, 12, 30, 5, 0, time.Local).Unix(), cal.Events[0].Timestamp.Unix())
+				assert.Equal(t, time.Date(2020, time.January, 1, 1, 23, 5, 0, time.Local).Unix(), cal.Events[0].Timestamp.Unix())
 			},
 		},
-		"DATE-TIME (5-digit time / 2-digit hour (UTC))": {
+		"DATE-TIME (5-digit time HMMSS (UTC))": {
 			items: []lex.Item{
 				testutil.BeginCalendar(),
 				testutil.BeginEvent(),
@@ -237,7 +210,7 @@ func TestItems_timeParsing(t *testing.T) {
 				testutil.EndCalendar(),
 			},
 			expect: func(t *testing.T, cal parse.Calendar) {
-				assert.Equal(t, time.Date(2020, time.January, 1, 12, 30, 5, 0, time.UTC).Unix(), cal.Events[0].Timestamp.Unix())
+				assert.Equal(t, time.Date(2020, time.January, 1, 1, 23, 5, 0, time.UTC).Unix(), cal.Events[0].Timestamp.Unix())
 			},
 		},
 		"DATE-TIME (malformed as DATE)": {
@@ -285,7 +258,7 @@ func TestItems_timeParsing(t *testing.T) {
 				assert.Equal(t, time.Date(2020, time.January, 1, 10, 30, 20, 0, time.UTC).Unix(), cal.Events[0].Timestamp.Unix())
 			},
 		},
-		"with TZID param": {
+		"DATE with TZID param (ignored)": {
 			items: []lex.Item{
 				testutil.BeginCalendar(),
 				testutil.BeginEvent(),
@@ -299,11 +272,24 @@ func TestItems_timeParsing(t *testing.T) {
 				testutil.EndCalendar(),
 			},
 			expect: func(t *testing.T, cal parse.Calendar) {
-				loc, err := time.LoadLocation("America/New_York")
-				if err != nil {
-					t.Fatal(err)
-				}
-				assert.Equal(t, time.Date(2020, time.January, 1, 0, 0, 0, 0, loc).Unix(), cal.Events[0].Timestamp.Unix())
+				assert.Equal(t, time.Date(2020, time.January, 1, 0, 0, 0, 0, time.Local), cal.Events[0].Timestamp)
+			},
+		},
+		"DATE-TIME with TZID param": {
+			items: []lex.Item{
+				testutil.BeginCalendar(),
+				testutil.BeginEvent(),
+				testutil.Item(lex.Name, "DTSTAMP"),
+				testutil.Item(lex.ParamName, "VALUE"),
+				testutil.Item(lex.ParamValue, "DATE-TIME"),
+				testutil.Item(lex.ParamName, "TZID"),
+				testutil.Item(lex.ParamValue, "America/New_York"),
+				testutil.Item(lex.Value, "20200101T100000"),
+				testutil.EndEvent(),
+				testutil.EndCalendar(),
+			},
+			expect: func(t *testing.T, cal parse.Calendar) {
+				assert.Equal(t, time.Date(2020, time.January, 1, 10, 0, 0, 0, testutil.LoadLocation("America/New_York")), cal.Events[0].Timestamp)
 			},
 		},
 	}
@@ -317,6 +303,39 @@ func TestItems_timeParsing(t *testing.T) {
 	}
 }
 
+func TestItems_shortTime(t *testing.T) {
+	tests := map[string]time.Time{
+		"20200101T1030Z":   time.Date(2020, time.January, 1, 10, 30, 0, 0, time.UTC),
+		"20200101T103045Z": time.Date(2020, time.January, 1, 10, 30, 45, 0, time.UTC),
+		"20200101T0905":    time.Date(2020, time.January, 1, 9, 5, 0, 0, time.Local),
+		"20200101T1358":    time.Date(2020, time.January, 1, 13, 58, 0, 0, time.Local),
+		"20200101T930":     time.Date(2020, time.January, 1, 9, 30, 0, 0, time.Local),
+		"20200101T930Z":    time.Date(2020, time.January, 1, 9, 30, 0, 0, time.UTC),
+		"20200101T93015":   time.Date(2020, time.January, 1, 9, 30, 15, 0, time.Local),
+		"20200101T93015Z":  time.Date(2020, time.January, 1, 9, 30, 15, 0, time.UTC),
+		"20200101T235959":  time.Date(2020, time.January, 1, 23, 59, 59, 0, time.Local),
+	}
+
+	for val, expected := range tests {
+		t.Run(val, func(t *testing.T) {
+			cal, err := parse.Items(lex.Text("BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTAMP:" + val + "\nEND:VEVENT\nEND:VCALENDAR"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, expected.Equal(cal.Events[0].Timestamp), "expected %v; got %v", expected, cal.Events[0].Timestamp)
+		})
+	}
+}
+
+func TestItems_shortTime_invalid(t *testing.T) {
+	for _, val := range []string{"20200101T2560", "20200101T3158Z", "20200101T1260", "20200101T960", "20200101T16060"} {
+		t.Run(val, func(t *testing.T) {
+			_, err := parse.Items(lex.Text("BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTAMP:" + val + "\nEND:VEVENT\nEND:VCALENDAR"))
+			assert.Error(t, err)
+		})
+	}
+}
+
 func TestItems_inclusiveEnds(t *testing.T) {
 	tests := map[string]struct {
 		inclusive bool
@@ -364,6 +383,45 @@ func TestItems_inclusiveEnds(t *testing.T) {
 				End:   time.Date(2020, time.January, 11, 0, 0, 0, 0, time.Local),
 			},
 		},
+		"inclusive without DTEND": {
+			inclusive: true,
+			items: []lex.Item{
+				testutil.BeginCalendar(),
+				testutil.BeginEvent(),
+				testutil.Item(lex.Name, "DTSTART"),
+				testutil.Item(lex.Value, "20200101"),
+				testutil.EndEvent(),
+				testutil.EndCalendar(),
+			},
+			expected: parse.Event{
+				Properties: []parse.Property{
+					testutil.Property("DTSTART", "20200101", nil),
+				},
+				Start: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.Local),
+				End:   time.Date(2020, time.January, 2, 0, 0, 0, 0, time.Local),
+			},
+		},
+		"inclusive with DATE-TIME DTEND": {
+			inclusive: true,
+			items: []lex.Item{
+				testutil.BeginCalendar(),
+				testutil.BeginEvent(),
+				testutil.Item(lex.Name, "DTSTART"),
+				testutil.Item(lex.Value, "20200101T100000Z"),
+				testutil.Item(lex.Name, "DTEND"),
+				testutil.Item(lex.Value, "20200103T100000Z"),
+				testutil.EndEvent(),
+				testutil.EndCalendar(),
+			},
+			expected: parse.Event{
+				Properties: []parse.Property{
+					testutil.Property("DTSTART", "20200101T100000Z", nil),
+					testutil.Property("DTEND", "20200103T100000Z", nil),
+				},
+				Start: time.Date(2020, time.January, 1, 10, 0, 0, 0, time.UTC),
+				End:   time.Date(2020, time.January, 3, 10, 0, 0, 0, time.UTC),
+			},
+		},
 	}
 
 	for name, test := range tests {
@@ -383,6 +441,249 @@ func TestItems_inclusiveEnds(t *testing.T) {
 	}
 }
 
+func TestItems_fillDTSTAMP(t *testing.T) {
+	now := time.Date(2020, time.March, 4, 12, 30, 15, 999, time.FixedZone("UTC+2", 2*60*60))
+	clock := parse.Clock(func() time.Time { return now })
+
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:1
+END:VEVENT
+BEGIN:VEVENT
+UID:2
+DTSTAMP:20200101T100000Z
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input), clock, parse.FillDTSTAMP)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, time.Date(2020, time.March, 4, 10, 30, 15, 0, time.UTC), cal.Events[0].Timestamp)
+	assert.Equal(t, []parse.Property{
+		testutil.Property("UID", "1", nil),
+		testutil.Property("DTSTAMP", "20200304T103015Z", nil),
+	}, cal.Events[0].Properties)
+
+	assert.Equal(t, time.Date(2020, time.January, 1, 10, 0, 0, 0, time.UTC), cal.Events[1].Timestamp)
+	assert.Len(t, cal.Events[1].Properties, 2)
+
+	cal, err = parse.Items(lex.Text(input), clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, cal.Events[0].Timestamp.IsZero())
+	assert.Len(t, cal.Events[0].Properties, 1)
+}
+
+func TestItems_normalizeToUTC(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+DTSTART;TZID=America/New_York:20200101T100000
+DTEND;TZID=Europe/Berlin:20200101T180000
+DTSTAMP:20200101T100000
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input), parse.NormalizeToUTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evt := cal.Events[0]
+	assert.Equal(t, time.Date(2020, time.January, 1, 15, 0, 0, 0, time.UTC), evt.Start)
+	assert.Equal(t, time.Date(2020, time.January, 1, 17, 0, 0, 0, time.UTC), evt.End)
+
+	// floating time
+	assert.Equal(t, time.Date(2020, time.January, 1, 10, 0, 0, 0, time.Local), evt.Timestamp)
+}
+
+func TestItems_lenientDates(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+DTSTART:20200101T235960Z
+END:VEVENT
+END:VCALENDAR`
+
+	_, err := parse.Items(lex.Text(input))
+	assert.Error(t, err)
+
+	cal, err := parse.Items(lex.Text(input), parse.LenientDates)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, time.Date(2020, time.January, 1, 23, 59, 59, 0, time.UTC), cal.Events[0].Start)
+}
+
+func TestItems_geo(t *testing.T) {
+	semicolon := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nGEO:37.386013;-122.082932\nEND:VEVENT\nEND:VCALENDAR"
+	comma := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nGEO:37.386013,-122.082932\nEND:VEVENT\nEND:VCALENDAR"
+	expected := &parse.GeoPoint{Latitude: 37.386013, Longitude: -122.082932}
+
+	cal, err := parse.Items(lex.Text(semicolon))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, expected, cal.Events[0].Geo)
+
+	_, err = parse.Items(lex.Text(comma))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `GEO: invalid value "37.386013,-122.082932"`)
+
+	for _, input := range []string{semicolon, comma} {
+		cal, err = parse.Items(lex.Text(input), parse.LenientGeo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, expected, cal.Events[0].Geo)
+	}
+}
+
+func TestItems_tzidWithUTCValue(t *testing.T) {
+	input := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART;TZID=America/New_York:20200101T103000Z\nEND:VEVENT\nEND:VCALENDAR"
+
+	_, err := parse.Items(lex.Text(input))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `TZID "America/New_York" conflicts with UTC value "20200101T103000Z"`)
+
+	cal, err := parse.Items(lex.Text(input), parse.LenientDates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, time.Date(2020, time.January, 1, 10, 30, 0, 0, time.UTC), cal.Events[0].Start)
+}
+
+func TestItems_lineLength(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\nX-LONG:" + strings.Repeat("a", 193) + "\r\nEND:VCALENDAR"
+
+	cal, err := parse.Items(lex.Text(input, lex.MaxOctetsPerLine(75)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"line 2 has 200 octets; the maximum is 75"}, cal.Warnings)
+
+	_, err = parse.Items(lex.Text(input, lex.MaxOctetsPerLine(75)), parse.CheckLineLength)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2 has 200 octets")
+}
+
+func TestItems_multiValueDTSTART(t *testing.T) {
+	input := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20200101,20200102\nEND:VEVENT\nEND:VCALENDAR"
+
+	_, err := parse.Items(lex.Text(input))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `DTSTART must have a single value; got "20200101,20200102"`)
+
+	cal, err := parse.Items(lex.Text(input), parse.LenientDates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, time.Date(2020, time.January, 1, 0, 0, 0, 0, time.Local), cal.Events[0].Start)
+}
+
+func TestItems_keepProperties(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:1
+DTSTART:20200101T100000Z
+DTEND:20200101T110000Z
+SUMMARY:Meeting
+DESCRIPTION:A long description
+X-CUSTOM:foo
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input), parse.KeepProperties("UID", "DTSTART"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evt := cal.Events[0]
+	assert.Equal(t, []parse.Property{
+		testutil.Property("UID", "1", nil),
+		testutil.Property("DTSTART", "20200101T100000Z", nil),
+	}, evt.Properties)
+	assert.Equal(t, "Meeting", evt.Summary)
+	assert.Equal(t, "A long description", evt.Description)
+	assert.Equal(t, time.Date(2020, time.January, 1, 11, 0, 0, 0, time.UTC), evt.End)
+}
+
+func TestItems_dateWithTZID(t *testing.T) {
+	input := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART;VALUE=DATE;TZID=America/New_York:20200101\nEND:VEVENT\nEND:VCALENDAR"
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, time.Date(2020, time.January, 1, 0, 0, 0, 0, time.Local), cal.Events[0].Start)
+
+	cal, err = parse.Items(lex.Text(input), parse.LenientDates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, time.Date(2020, time.January, 1, 0, 0, 0, 0, testutil.LoadLocation("America/New_York")), cal.Events[0].Start)
+}
+
+func TestItems_fieldMapper(t *testing.T) {
+	type altDesc struct {
+		fmtType string
+		value   string
+	}
+
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:1
+SUMMARY:Meeting
+DESCRIPTION:Plain description
+X-ALT-DESC;FMTTYPE=text/html:<p>HTML description</p>
+END:VEVENT
+END:VCALENDAR`
+
+	var descs []altDesc
+	cal, err := parse.Items(
+		lex.Text(input),
+		parse.FieldMapper("X-ALT-DESC", func(evt *parse.Event, prop parse.Property) {
+			fmtType, _ := prop.Params.First("FMTTYPE")
+			descs = append(descs, altDesc{fmtType: fmtType, value: prop.Value})
+		}),
+		parse.FieldMapper("SUMMARY", func(evt *parse.Event, prop parse.Property) {
+			evt.Summary = strings.ToUpper(prop.Value)
+		}),
+		parse.FieldMapper("DESCRIPTION", nil),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []altDesc{{fmtType: "text/html", value: "<p>HTML description</p>"}}, descs)
+
+	evt := cal.Events[0]
+	assert.Equal(t, "1", evt.UID)
+	assert.Equal(t, "MEETING", evt.Summary)
+	assert.Equal(t, "", evt.Description)
+}
+
+func TestItems_decodeCalAddress(t *testing.T) {
+	input := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nATTENDEE:mailto:a%40b.com\nATTENDEE:mailto:c%zz\nEND:VEVENT\nEND:VCALENDAR"
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "a%40b.com", cal.Events[0].Attendees[0].Email)
+
+	cal, err = parse.Items(lex.Text(input), parse.DecodeCalAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "mailto:a%40b.com", cal.Events[0].Attendees[0].Address)
+	assert.Equal(t, "a@b.com", cal.Events[0].Attendees[0].Email)
+	assert.Equal(t, "c%zz", cal.Events[0].Attendees[1].Email)
+}
+
 func TestItems_paramValues(t *testing.T) {
 	tests := map[string]struct {
 		items  []lex.Item
@@ -489,6 +790,42 @@ func TestItems_context(t *testing.T) {
 	assert.Equal(t, &parse.Error{Err: ctx.Err()}, err)
 }
 
+func TestItems_skipErrors(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:1
+DTSTART:2020-01-01
+END:VEVENT
+BEGIN:VEVENT
+UID:2
+DTSTART:20200101T100000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:3
+RRULE:FREQ=SOMETIMES
+END:VEVENT
+END:VCALENDAR`
+
+	_, err := parse.Items(lex.Text(input))
+	assert.Error(t, err)
+
+	cal, err := parse.Items(lex.Text(input), parse.SkipErrors)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, cal.Events, 1)
+	assert.Equal(t, "2", cal.Events[0].UID)
+	assert.Len(t, cal.Errors, 2)
+
+	var perr *parse.Error
+	assert.True(t, errors.As(cal.Errors, &perr))
+	assert.Equal(t, cal.Errors[0], perr)
+
+	var timeErr *time.ParseError
+	assert.True(t, errors.As(cal.Errors, &timeErr))
+}
+
 func TestItems_event(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -517,6 +854,24 @@ DURATION:P12DT5H2M10S`,
 					Add(10 * time.Second), // 10S
 			},
 		},
+		{
+			name: "DTEND before DTSTART",
+			body: `DTEND;VALUE=DATE-TIME:20200101T120000Z
+DTSTART;VALUE=DATE-TIME:20200101T103000Z`,
+			expected: parse.Event{
+				Start: time.Date(2020, time.January, 1, 10, 30, 0, 0, time.UTC),
+				End:   time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "DURATION before DTSTART",
+			body: `DURATION:PT1H30M
+DTSTART;VALUE=DATE-TIME:20200101T103000Z`,
+			expected: parse.Event{
+				Start: time.Date(2020, time.January, 1, 10, 30, 0, 0, time.UTC),
+				End:   time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC),
+			},
+		},
 		{
 			name: "implicit 1-day duration",
 			body: `DTSTART:20200101`,
@@ -533,12 +888,183 @@ DURATION:P12DT5H2M10S`,
 				End:   time.Date(2020, time.January, 2, 0, 0, 0, 0, time.Local),
 			},
 		},
+		{
+			name: "implicit 1-day duration (VALUE=DATE)",
+			body: `DTSTART;VALUE=DATE:20200101`,
+			expected: parse.Event{
+				Start: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.Local),
+				End:   time.Date(2020, time.January, 2, 0, 0, 0, 0, time.Local),
+			},
+		},
+		{
+			name: "implicit zero duration without VALUE param",
+			body: `DTSTART:20200101T103020`,
+			expected: parse.Event{
+				Start: time.Date(2020, time.January, 1, 10, 30, 20, 0, time.Local),
+				End:   time.Date(2020, time.January, 1, 10, 30, 20, 0, time.Local),
+			},
+		},
+		{
+			name: "implicit zero duration without VALUE param (UTC)",
+			body: `DTSTART:20200101T103020Z`,
+			expected: parse.Event{
+				Start: time.Date(2020, time.January, 1, 10, 30, 20, 0, time.UTC),
+				End:   time.Date(2020, time.January, 1, 10, 30, 20, 0, time.UTC),
+			},
+		},
+		{
+			name: "conferences",
+			body: `CONFERENCE;VALUE=URI;FEATURE=AUDIO,VIDEO;LABEL="Team call: Zoom":https://zoom.example.com/j/123
+CONFERENCE;VALUE=URI;FEATURE=PHONE:tel:+1-555-0100`,
+			expected: parse.Event{
+				Conferences: []parse.Conference{
+					{
+						URI:      "https://zoom.example.com/j/123",
+						Features: []string{"AUDIO", "VIDEO"},
+						Label:    "Team call: Zoom",
+					},
+					{
+						URI:      "tel:+1-555-0100",
+						Features: []string{"PHONE"},
+					},
+				},
+			},
+		},
+		{
+			name: "attendees",
+			body: `ATTENDEE;CN=John Doe;ROLE=REQ-PARTICIPANT;PARTSTAT=ACCEPTED;RSVP=TRUE:mailto:jdoe@example.com
+ATTENDEE;RSVP=false:mailto:jsmith@example.com
+ATTENDEE:mailto:boss@example.com
+ATTENDEE;RSVP=YES:mailto:jane@example.com`,
+			expected: parse.Event{
+				Attendees: []parse.Attendee{
+					{
+						Address:    "mailto:jdoe@example.com",
+						Email:      "jdoe@example.com",
+						CommonName: "John Doe",
+						Role:       "REQ-PARTICIPANT",
+						PartStat:   "ACCEPTED",
+						RSVP:       boolPtr(true),
+					},
+					{
+						Address: "mailto:jsmith@example.com",
+						Email:   "jsmith@example.com",
+						RSVP:    boolPtr(false),
+					},
+					{
+						Address: "mailto:boss@example.com",
+						Email:   "boss@example.com",
+					},
+					{
+						Address: "mailto:jane@example.com",
+						Email:   "jane@example.com",
+					},
+				},
+			},
+		},
+		{
+			name: "attendees with other address schemes",
+			body: `ATTENDEE;CN=Room 1:urn:uuid:6f1e2d3c-4b5a-4978-8a9b-0c1d2e3f4a5b
+ORGANIZER:https://example.com/users/jdoe`,
+			expected: parse.Event{
+				Organizer: parse.Organizer{Address: "https://example.com/users/jdoe"},
+				Attendees: []parse.Attendee{{
+					Address:    "urn:uuid:6f1e2d3c-4b5a-4978-8a9b-0c1d2e3f4a5b",
+					CommonName: "Room 1",
+				}},
+			},
+		},
+		{
+			name: "scheduling params",
+			body: `ORGANIZER;CN=Jane Doe;SCHEDULE-AGENT=server:mailto:jane@example.com
+ATTENDEE;SCHEDULE-STATUS="2.0";SCHEDULE-AGENT=CLIENT:mailto:jdoe@example.com
+ATTENDEE;SCHEDULE-STATUS="3.7","5.3":mailto:jsmith@example.com`,
+			expected: parse.Event{
+				Organizer: parse.Organizer{
+					Address:       "mailto:jane@example.com",
+					Email:         "jane@example.com",
+					CommonName:    "Jane Doe",
+					ScheduleAgent: "SERVER",
+				},
+				Attendees: []parse.Attendee{
+					{
+						Address:        "mailto:jdoe@example.com",
+						Email:          "jdoe@example.com",
+						ScheduleAgent:  "CLIENT",
+						ScheduleStatus: []string{"2.0"},
+					},
+					{
+						Address:        "mailto:jsmith@example.com",
+						Email:          "jsmith@example.com",
+						ScheduleStatus: []string{"3.7", "5.3"},
+					},
+				},
+			},
+		},
+		{
+			name: "attachments",
+			body: `ATTACH;FMTTYPE=application/pdf;FILENAME=agenda.pdf;SIZE=12345;MANAGED-ID=97S:https://files.example.com/agenda.pdf
+ATTACH;FMTTYPE=text/plain;ENCODING=BASE64;VALUE=BINARY:SGVsbG8=`,
+			expected: parse.Event{
+				Attachments: []parse.Attachment{
+					{
+						URI:        "https://files.example.com/agenda.pdf",
+						FormatType: "application/pdf",
+						Filename:   "agenda.pdf",
+						Size:       12345,
+						ManagedID:  "97S",
+					},
+					{
+						Data:       []byte("Hello"),
+						FormatType: "text/plain",
+					},
+				},
+			},
+		},
+		{
+			name: "invalid attachment params and data",
+			body: `ATTACH;SIZE=12kB:https://files.example.com/agenda.pdf
+ATTACH;FMTTYPE=text/plain;ENCODING=BASE64;VALUE=BINARY:not base64!`,
+			expected: parse.Event{
+				Attachments: []parse.Attachment{
+					{URI: "https://files.example.com/agenda.pdf"},
+					{FormatType: "text/plain"},
+				},
+			},
+		},
+		{
+			name: "escaped text",
+			body: `SUMMARY:Lunch\, then meeting\; bring notes
+DESCRIPTION:Line 1\nLine 2 in C:\\Docs
+URL:https://example.com/files\share`,
+			expected: parse.Event{
+				Summary:      "Lunch, then meeting; bring notes",
+				Summaries:    []parse.LocalizedText{{Value: "Lunch, then meeting; bring notes"}},
+				Description:  "Line 1\nLine 2 in C:\\Docs",
+				Descriptions: []parse.LocalizedText{{Value: "Line 1\nLine 2 in C:\\Docs"}},
+				URL:          `https://example.com/files\share`,
+			},
+		},
+		{
+			name: "request status",
+			body: `REQUEST-STATUS:2.0;Success
+REQUEST-STATUS:3.7;Invalid calendar user;mailto:foo@example.com
+REQUEST-STATUS:3.1;Invalid property value;DTSTART:96-Apr-01`,
+			expected: parse.Event{
+				RequestStatus: []parse.RequestStatus{
+					{Code: "2.0", Description: "Success"},
+					{Code: "3.7", Description: "Invalid calendar user", ExtraData: "mailto:foo@example.com"},
+					{Code: "3.1", Description: "Invalid property value", ExtraData: "DTSTART:96-Apr-01"},
+				},
+			},
+		},
 		{
 			name: "summary",
 			body: `SUMMARY:This is a
   folded summary`,
 			expected: parse.Event{
-				Summary: "This is a folded summary",
+				Summary:   "This is a folded summary",
+				Summaries: []parse.LocalizedText{{Value: "This is a folded summary"}},
 			},
 		},
 		{
@@ -546,7 +1072,92 @@ DURATION:P12DT5H2M10S`,
 			body: `DESCRIPTION;FMTTYPE=text/plain:A description with a parameter. Also
   folded :)`,
 			expected: parse.Event{
-				Description: "A description with a parameter. Also folded :)",
+				Description:  "A description with a parameter. Also folded :)",
+				Descriptions: []parse.LocalizedText{{Value: "A description with a parameter. Also folded :)"}},
+			},
+		},
+		{
+			name: "unknown value type",
+			body: `DTSTART:20200101T100000Z
+DTEND;VALUE=XML:<end>20200101</end>`,
+			expected: parse.Event{
+				Start: time.Date(2020, time.January, 1, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "categories",
+			body: `CATEGORIES:APPOINTMENT,EDUCATION
+CATEGORIES:MEETING\, WEEKLY,A\\B`,
+			expected: parse.Event{
+				Categories: []string{"APPOINTMENT", "EDUCATION", "MEETING, WEEKLY", `A\B`},
+			},
+		},
+		{
+			name: "custom component",
+			body: `SUMMARY:With component
+BEGIN:X-CUSTOM
+X-FOO:bar
+BEGIN:X-NESTED
+END:X-NESTED
+END:X-CUSTOM`,
+			expected: parse.Event{
+				Summary:   "With component",
+				Summaries: []parse.LocalizedText{{Value: "With component"}},
+				Components: []parse.Component{{
+					Name:       "X-CUSTOM",
+					Properties: []parse.Property{testutil.Property("X-FOO", "bar", nil)},
+					Components: []parse.Component{{Name: "X-NESTED"}},
+				}},
+			},
+		},
+		{
+			name: "description with altrep",
+			body: `DESCRIPTION;ALTREP="cid:part1.0001@example.org":The Fall'98 Wild Wizards Conference`,
+			expected: parse.Event{
+				Description:       "The Fall'98 Wild Wizards Conference",
+				Descriptions:      []parse.LocalizedText{{Value: "The Fall'98 Wild Wizards Conference"}},
+				DescriptionAltRep: "cid:part1.0001@example.org",
+			},
+		},
+		{
+			name: "location",
+			body: `LOCATION;ALTREP="http://xyzcorp.com/conf-rooms/f123.vcf":Conference Room - F123, Bldg. 002`,
+			expected: parse.Event{
+				Location:       "Conference Room - F123, Bldg. 002",
+				LocationAltRep: "http://xyzcorp.com/conf-rooms/f123.vcf",
+			},
+		},
+		{
+			name: "language",
+			body: `SUMMARY;LANGUAGE=de:Besprechung\, Raum 2
+DESCRIPTION;LANGUAGE=de:Wöchentliche Besprechung`,
+			expected: parse.Event{
+				Summary:             "Besprechung, Raum 2",
+				SummaryLanguage:     "de",
+				Summaries:           []parse.LocalizedText{{Value: "Besprechung, Raum 2", Language: "de"}},
+				Description:         "Wöchentliche Besprechung",
+				DescriptionLanguage: "de",
+				Descriptions:        []parse.LocalizedText{{Value: "Wöchentliche Besprechung", Language: "de"}},
+			},
+		},
+		{
+			name: "multiple languages",
+			body: `SUMMARY;LANGUAGE=en:Meeting
+SUMMARY;LANGUAGE=fr:Réunion
+DESCRIPTION;LANGUAGE=fr:Réunion hebdomadaire
+DESCRIPTION:Weekly meeting`,
+			expected: parse.Event{
+				Summary:         "Meeting",
+				SummaryLanguage: "en",
+				Summaries: []parse.LocalizedText{
+					{Value: "Meeting", Language: "en"},
+					{Value: "Réunion", Language: "fr"},
+				},
+				Description: "Weekly meeting",
+				Descriptions: []parse.LocalizedText{
+					{Value: "Réunion hebdomadaire", Language: "fr"},
+					{Value: "Weekly meeting"},
+				},
 			},
 		},
 	}
@@ -593,8 +1204,160 @@ END:VALARM`,
 						"FMTTYPE": []string{"audio/basic"},
 					}),
 				},
-				Action:  "AUDIO",
-				Trigger: "19970317T133000Z",
+				Action:   "AUDIO",
+				Trigger:  parse.Trigger{Time: time.Date(1997, time.March, 17, 13, 30, 0, 0, time.UTC)},
+				Repeat:   4,
+				Duration: 15 * time.Minute,
+			}},
+		},
+		{
+			name: "relative trigger",
+			body: `BEGIN:VALARM
+TRIGGER:-PT1H
+ACTION:DISPLAY
+END:VALARM
+BEGIN:VALARM
+TRIGGER;RELATED=end:PT5M
+ACTION:DISPLAY
+END:VALARM`,
+			expected: []parse.Alarm{
+				{
+					Properties: []parse.Property{
+						testutil.Property("TRIGGER", "-PT1H", nil),
+						testutil.Property("ACTION", "DISPLAY", nil),
+					},
+					Action:  "DISPLAY",
+					Trigger: parse.Trigger{Duration: -time.Hour, Related: "START"},
+				},
+				{
+					Properties: []parse.Property{
+						testutil.Property("TRIGGER", "PT5M", parse.Parameters{
+							"RELATED": []string{"end"},
+						}),
+						testutil.Property("ACTION", "DISPLAY", nil),
+					},
+					Action:  "DISPLAY",
+					Trigger: parse.Trigger{Duration: 5 * time.Minute, Related: "END"},
+				},
+			},
+		},
+		{
+			name: "proximity alarm",
+			body: `BEGIN:VALARM
+ACTION:NONE
+TRIGGER;VALUE=DATE-TIME:19760401T005545Z
+PROXIMITY:arrive
+ACKNOWLEDGED:20200101T100000Z
+BEGIN:VLOCATION
+UID:123456-abcdef-98765432
+NAME:Office
+URL:geo:40.443,-79.945;u=10
+END:VLOCATION
+END:VALARM`,
+			expected: []parse.Alarm{{
+				Properties: []parse.Property{
+					testutil.Property("ACTION", "NONE", nil),
+					testutil.Property("TRIGGER", "19760401T005545Z", parse.Parameters{
+						"VALUE": []string{"DATE-TIME"},
+					}),
+					testutil.Property("PROXIMITY", "arrive", nil),
+					testutil.Property("ACKNOWLEDGED", "20200101T100000Z", nil),
+				},
+				Action:       "NONE",
+				Trigger:      parse.Trigger{Time: time.Date(1976, time.April, 1, 0, 55, 45, 0, time.UTC)},
+				Proximity:    "ARRIVE",
+				Acknowledged: time.Date(2020, time.January, 1, 10, 0, 0, 0, time.UTC),
+				Components: []parse.Component{{
+					Name: "VLOCATION",
+					Properties: []parse.Property{
+						testutil.Property("UID", "123456-abcdef-98765432", nil),
+						testutil.Property("NAME", "Office", nil),
+						testutil.Property("URL", "geo:40.443,-79.945;u=10", nil),
+					},
+				}},
+			}},
+		},
+		{
+			name: "trigger without value type",
+			body: `BEGIN:VALARM
+TRIGGER:20200101T090000Z
+ACTION:DISPLAY
+END:VALARM
+BEGIN:VALARM
+TRIGGER:soon
+ACTION:DISPLAY
+END:VALARM`,
+			expected: []parse.Alarm{
+				{
+					Properties: []parse.Property{
+						testutil.Property("TRIGGER", "20200101T090000Z", nil),
+						testutil.Property("ACTION", "DISPLAY", nil),
+					},
+					Action:  "DISPLAY",
+					Trigger: parse.Trigger{Time: time.Date(2020, time.January, 1, 9, 0, 0, 0, time.UTC)},
+				},
+				{
+					Properties: []parse.Property{
+						testutil.Property("TRIGGER", "soon", nil),
+						testutil.Property("ACTION", "DISPLAY", nil),
+					},
+					Action: "DISPLAY",
+				},
+			},
+		},
+		{
+			name: "invalid repeat and duration",
+			body: `BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER:-PT15M
+REPEAT:x
+DURATION:15M
+END:VALARM
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER:-PT15M
+REPEAT:99999999999999
+DURATION:PT5M
+END:VALARM`,
+			expected: []parse.Alarm{
+				{
+					Properties: []parse.Property{
+						testutil.Property("ACTION", "DISPLAY", nil),
+						testutil.Property("TRIGGER", "-PT15M", nil),
+						testutil.Property("REPEAT", "x", nil),
+						testutil.Property("DURATION", "15M", nil),
+					},
+					Action:  "DISPLAY",
+					Trigger: parse.Trigger{Duration: -15 * time.Minute, Related: "START"},
+				},
+				{
+					Properties: []parse.Property{
+						testutil.Property("ACTION", "DISPLAY", nil),
+						testutil.Property("TRIGGER", "-PT15M", nil),
+						testutil.Property("REPEAT", "99999999999999", nil),
+						testutil.Property("DURATION", "PT5M", nil),
+					},
+					Action:   "DISPLAY",
+					Trigger:  parse.Trigger{Duration: -15 * time.Minute, Related: "START"},
+					Duration: 5 * time.Minute,
+				},
+			},
+		},
+		{
+			name: "invalid acknowledged",
+			body: `BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER:-PT15M
+ACKNOWLEDGED:yesterday
+END:VALARM`,
+			expected: []parse.Alarm{{
+				Properties: []parse.Property{
+					testutil.Property("ACTION", "DISPLAY", nil),
+					testutil.Property("TRIGGER", "-PT15M", nil),
+					testutil.Property("ACKNOWLEDGED", "yesterday", nil),
+				},
+				Action:  "DISPLAY",
+				Trigger: parse.Trigger{Duration: -15 * time.Minute, Related: "START"},
 			}},
 		},
 	}
@@ -619,3 +1382,591 @@ END:VALARM`,
 		})
 	}
 }
+
+func TestParser_Reset(t *testing.T) {
+	p := parse.NewParser()
+
+	p.Reset(lex.Text("BEGIN:VCALENDAR\nVERSION:2.0\nBEGIN:VEVENT\nUID:1\nEND:VEVENT\nEND:VCALENDAR"))
+	first, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.Reset(lex.Text("BEGIN:VCALENDAR\nMETHOD:PUBLISH\nBEGIN:VEVENT\nUID:2\nEND:VEVENT\nEND:VCALENDAR"))
+	second, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "2.0", first.Version)
+	assert.Equal(t, "1", first.Events[0].UID)
+
+	assert.Equal(t, "", second.Version)
+	assert.Equal(t, "PUBLISH", second.Method)
+	assert.Len(t, second.Events, 1)
+	assert.Equal(t, "2", second.Events[0].UID)
+}
+
+func TestParser_zeroValue(t *testing.T) {
+	var p parse.Parser
+	parse.FillDTSTAMP(&p)
+
+	p.Reset(lex.Text("BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:1\nEND:VEVENT\nEND:VCALENDAR"))
+	cal, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "1", cal.Events[0].UID)
+	assert.False(t, cal.Events[0].Timestamp.IsZero())
+}
+
+const benchmarkFiles = 10000
+
+var benchmarkItems = []lex.Item{
+	testutil.BeginCalendar(),
+	testutil.Item(lex.Name, "VERSION"),
+	testutil.Item(lex.Value, "2.0"),
+	testutil.BeginEvent(),
+	testutil.Item(lex.Name, "UID"),
+	testutil.Item(lex.Value, "111111111111"),
+	testutil.Item(lex.Name, "DTSTART"),
+	testutil.Item(lex.ParamName, "VALUE"),
+	testutil.Item(lex.ParamValue, "DATE-TIME"),
+	testutil.Item(lex.Value, "20200101T103000Z"),
+	testutil.Item(lex.Name, "DTEND"),
+	testutil.Item(lex.ParamName, "VALUE"),
+	testutil.Item(lex.ParamValue, "DATE-TIME"),
+	testutil.Item(lex.Value, "20200101T113000Z"),
+	testutil.EndEvent(),
+	testutil.EndCalendar(),
+}
+
+func BenchmarkItems(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for f := 0; f < benchmarkFiles; f++ {
+			if _, err := parse.Items(testutil.LexItems(benchmarkItems...)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkParser_Reset(b *testing.B) {
+	b.ReportAllocs()
+	p := parse.NewParser()
+	for i := 0; i < b.N; i++ {
+		for f := 0; f < benchmarkFiles; f++ {
+			p.Reset(testutil.LexItems(benchmarkItems...))
+			if _, err := p.Parse(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func benchmarkInput(events int) string {
+	var b strings.Builder
+	b.WriteString("BEGIN:VCALENDAR\r\n")
+	for i := 0; i < events; i++ {
+		fmt.Fprintf(&b, "BEGIN:VEVENT\r\nUID:%d\r\nDTSTART:20200101T100000Z\r\nDTEND:20200101T110000Z\r\n", i)
+		b.WriteString("SUMMARY:Lorem ipsum dolor sit amet\r\n")
+		b.WriteString("DESCRIPTION:" + strings.Repeat("Lorem ipsum dolor sit amet. ", 20) + "\r\n")
+		b.WriteString("X-MICROSOFT-CDO-BUSYSTATUS:BUSY\r\nX-MICROSOFT-CDO-IMPORTANCE:1\r\n")
+		b.WriteString("END:VEVENT\r\n")
+	}
+	b.WriteString("END:VCALENDAR")
+	return b.String()
+}
+
+func benchmarkLargeCalendar(b *testing.B, opts ...parse.Option) {
+	input := benchmarkInput(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	var retained int
+	for i := 0; i < b.N; i++ {
+		cal, err := parse.Items(lex.Text(input), opts...)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		retained = 0
+		for _, evt := range cal.Events {
+			for _, prop := range evt.Properties {
+				retained += len(prop.Name) + len(prop.Value)
+			}
+		}
+	}
+	b.ReportMetric(float64(retained), "retained-B/op")
+}
+
+func BenchmarkItems_largeCalendar(b *testing.B) {
+	benchmarkLargeCalendar(b)
+}
+
+func BenchmarkItems_keepProperties(b *testing.B) {
+	benchmarkLargeCalendar(b, parse.KeepProperties("UID", "DTSTART", "DTEND", "SUMMARY"))
+}
+
+func TestItems_partialCalendar(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:1
+DTSTART:20200101T100000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:2
+DTSTART:20200102T100000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:3
+DTSTART:20200103T100000Z
+INVALID LINE
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	assert.Error(t, err)
+	assert.Equal(t, "2.0", cal.Version)
+	if assert.Len(t, cal.Events, 2) {
+		assert.Equal(t, "1", cal.Events[0].UID)
+		assert.Equal(t, "2", cal.Events[1].UID)
+	}
+
+	cal, err = parse.Items(lex.Text(input), parse.PartialEvents)
+	assert.Error(t, err)
+	if assert.Len(t, cal.Events, 3) {
+		assert.Equal(t, "3", cal.Events[2].UID)
+		assert.Equal(t, time.Date(2020, time.January, 3, 10, 0, 0, 0, time.UTC), cal.Events[2].Start)
+	}
+}
+
+func TestItems_crossZoneEvent(t *testing.T) {
+	newYork := testutil.LoadLocation("America/New_York")
+	london := testutil.LoadLocation("Europe/London")
+
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:flight
+DTSTART;TZID=America/New_York:20200301T190000
+DTEND;TZID=Europe/London:20200302T070000
+END:VEVENT
+END:VCALENDAR`
+
+	for _, opts := range [][]parse.Option{nil, {parse.InclusiveEnds}} {
+		cal, err := parse.Items(lex.Text(input), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		evt := cal.Events[0]
+		assert.Equal(t, time.Date(2020, time.March, 1, 19, 0, 0, 0, newYork), evt.Start)
+		assert.Equal(t, time.Date(2020, time.March, 2, 7, 0, 0, 0, london), evt.End)
+		assert.Equal(t, london, evt.End.Location())
+		assert.Equal(t, 7*time.Hour, evt.End.Sub(evt.Start))
+	}
+}
+
+func TestItems_detectDuplicateUIDs(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:1
+DTSTART:20200101T100000Z
+RRULE:FREQ=DAILY;COUNT=3
+END:VEVENT
+BEGIN:VEVENT
+UID:1
+RECURRENCE-ID:20200102T100000Z
+DTSTART:20200102T120000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:1
+DTSTART:20200105T100000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:2
+DTSTART:20200101T100000Z
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, cal.Events, 4)
+	assert.Empty(t, cal.Errors)
+
+	cal, err = parse.Items(lex.Text(input), parse.DetectDuplicateUIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, cal.Events, 4)
+	if assert.Len(t, cal.Errors, 1) {
+		assert.True(t, errors.Is(cal.Errors[0], parse.ErrDuplicateUID))
+		assert.Contains(t, cal.Errors[0].Error(), `"1"`)
+	}
+}
+
+func TestItems_headerOnly(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Example//Feed//EN
+X-WR-CALNAME:Work
+BEGIN:VTIMEZONE
+TZID:Europe/Berlin
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:1
+DTSTART:invalid
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input), parse.HeaderOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "2.0", cal.Version)
+	assert.Equal(t, "-//Example//Feed//EN", cal.ProductID)
+	assert.Len(t, cal.Properties, 3)
+	assert.Empty(t, cal.Components)
+	assert.Empty(t, cal.Events)
+}
+
+func TestItems_trailingCR(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:1\r\nEND:VEVENT\r\nEND:VCALENDAR\r"
+
+	cal, err := parse.Items(lex.Text(input, lex.StrictLineBreaks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "1", cal.Events[0].UID)
+}
+
+func TestNewEvent(t *testing.T) {
+	evt := parse.NewEvent(map[string]string{
+		"UID":      "1",
+		"SUMMARY":  "Meeting\\, weekly",
+		"DTSTART":  "20200101T100000Z",
+		"DURATION": "PT1H30M",
+	})
+
+	assert.Equal(t, "1", evt.UID)
+	assert.Equal(t, "Meeting, weekly", evt.Summary)
+	assert.Equal(t, time.Date(2020, time.January, 1, 10, 0, 0, 0, time.UTC), evt.Start)
+	assert.Equal(t, time.Date(2020, time.January, 1, 11, 30, 0, 0, time.UTC), evt.End)
+	assert.Equal(t, []parse.Property{
+		testutil.Property("DTSTART", "20200101T100000Z", nil),
+		testutil.Property("DURATION", "PT1H30M", nil),
+		testutil.Property("SUMMARY", "Meeting\\, weekly", nil),
+		testutil.Property("UID", "1", nil),
+	}, evt.Properties)
+}
+
+func TestNewEvent_invalidValue(t *testing.T) {
+	evt := parse.NewEvent(map[string]string{
+		"DTSTART": "bad",
+		"SUMMARY": "x",
+		"UID":     "1",
+	})
+
+	assert.Equal(t, "1", evt.UID)
+	assert.Equal(t, "x", evt.Summary)
+	assert.True(t, evt.Start.IsZero())
+	assert.Len(t, evt.Properties, 3)
+}
+
+func TestEventFromProperties(t *testing.T) {
+	evt, err := parse.EventFromProperties([]parse.Property{
+		testutil.Property("DTSTART", "20200101T100000", parse.Parameters{"TZID": {"Europe/Berlin"}}),
+		testutil.Property("DTEND", "20200101T110000", parse.Parameters{"TZID": {"Europe/Berlin"}}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	berlin := testutil.LoadLocation("Europe/Berlin")
+	assert.Equal(t, time.Date(2020, time.January, 1, 10, 0, 0, 0, berlin), evt.Start)
+	assert.Equal(t, time.Date(2020, time.January, 1, 11, 0, 0, 0, berlin), evt.End)
+
+	_, err = parse.EventFromProperties([]parse.Property{testutil.Property("DTSTART", "invalid", nil)})
+	assert.Error(t, err)
+}
+
+func TestItems_propertiesAfterEvents(t *testing.T) {
+	f, err := os.Open("testdata/properties_after_events.ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	cal, err := parse.Items(lex.Reader(f))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "-//Example//Product//EN", cal.ProductID)
+	assert.Equal(t, "PUBLISH", cal.Method)
+	assert.Equal(t, "2.0", cal.Version)
+	assert.Len(t, cal.Events, 2)
+}
+
+func TestItems_transformValue(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+PRODID:-//Example//Product//EN
+BEGIN:VEVENT
+UID:1
+SUMMARY:Weekly meeting
+DESCRIPTION:Agenda
+END:VEVENT
+END:VCALENDAR`
+
+	upperSummary := func(name, value string) string {
+		if name == "SUMMARY" {
+			return strings.ToUpper(value)
+		}
+		return value
+	}
+	suffix := func(name, value string) string {
+		if name == "SUMMARY" {
+			return value + "!"
+		}
+		return value
+	}
+
+	cal, err := parse.Items(lex.Text(input), parse.TransformValue(upperSummary), parse.TransformValue(suffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evt := cal.Events[0]
+	assert.Equal(t, "WEEKLY MEETING!", evt.Summary)
+	prop, _ := evt.Property("SUMMARY")
+	assert.Equal(t, "WEEKLY MEETING!", prop.Value)
+	assert.Equal(t, "Agenda", evt.Description)
+	assert.Equal(t, "-//Example//Product//EN", cal.ProductID)
+}
+
+func TestItems_yearMonth(t *testing.T) {
+	input := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:202001\nEND:VEVENT\nEND:VCALENDAR"
+
+	_, err := parse.Items(lex.Text(input))
+	assert.Error(t, err)
+
+	cal, err := parse.Items(lex.Text(input), parse.LenientDates)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evt := cal.Events[0]
+	assert.Equal(t, time.Date(2020, time.January, 1, 0, 0, 0, 0, time.Local), evt.Start)
+	assert.Equal(t, time.Date(2020, time.January, 2, 0, 0, 0, 0, time.Local), evt.End)
+	assert.Equal(t, []time.Time{evt.Start}, evt.Days())
+}
+
+func TestItems_maxEvents(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("BEGIN:VCALENDAR\nVERSION:2.0\n")
+	for i := 1; i <= 5; i++ {
+		fmt.Fprintf(&b, "BEGIN:VEVENT\nUID:%d\nEND:VEVENT\n", i)
+	}
+	b.WriteString("END:VCALENDAR")
+
+	cal, err := parse.Items(lex.Text(b.String()), parse.MaxEvents(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, cal.Truncated)
+	assert.Equal(t, "2.0", cal.Version)
+	if assert.Len(t, cal.Events, 3) {
+		assert.Equal(t, "3", cal.Events[2].UID)
+	}
+
+	cal, err = parse.Items(lex.Text(b.String()), parse.MaxEvents(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, cal.Truncated)
+	assert.Len(t, cal.Events, 5)
+}
+
+func TestItems_todoAlarm(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VTODO
+UID:todo-1
+SUMMARY:Submit report
+BEGIN:VALARM
+ACTION:DISPLAY
+DESCRIPTION:Report due
+TRIGGER:-PT30M
+END:VALARM
+END:VTODO
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []parse.Component{{
+		Name: "VTODO",
+		Properties: []parse.Property{
+			testutil.Property("UID", "todo-1", nil),
+			testutil.Property("SUMMARY", "Submit report", nil),
+		},
+		Components: []parse.Component{{
+			Name: "VALARM",
+			Properties: []parse.Property{
+				testutil.Property("ACTION", "DISPLAY", nil),
+				testutil.Property("DESCRIPTION", "Report due", nil),
+				testutil.Property("TRIGGER", "-PT30M", nil),
+			},
+		}},
+	}}, cal.Components)
+}
+
+func TestItems_floatingLocation(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:floating
+DTSTART:20200101T100000
+END:VEVENT
+BEGIN:VEVENT
+UID:zoned
+DTSTART;TZID=America/New_York:20200101T100000
+END:VEVENT
+BEGIN:VEVENT
+UID:utc
+DTSTART:20200101T100000Z
+END:VEVENT
+END:VCALENDAR`
+
+	berlin := testutil.LoadLocation("Europe/Berlin")
+	newYork := testutil.LoadLocation("America/New_York")
+
+	cal, err := parse.Items(lex.Text(input), parse.FloatingLocation(berlin))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, time.Date(2020, time.January, 1, 10, 0, 0, 0, berlin), cal.Events[0].Start)
+	assert.Equal(t, time.Date(2020, time.January, 1, 10, 0, 0, 0, newYork), cal.Events[1].Start)
+	assert.Equal(t, time.Date(2020, time.January, 1, 10, 0, 0, 0, time.UTC), cal.Events[2].Start)
+}
+
+func TestItemsAll(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+VERSION:2.0
+X-WR-CALNAME:Work
+METHOD:PUBLISH
+BEGIN:VEVENT
+UID:work-1
+DTSTART:20200101T100000Z
+END:VEVENT
+END:VCALENDAR
+BEGIN:VCALENDAR
+X-WR-CALNAME:Home
+BEGIN:VEVENT
+UID:home-1
+DTSTART:20200102T100000Z
+END:VEVENT
+END:VCALENDAR
+`
+
+	cals, err := parse.ItemsAll(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, cals, 2)
+
+	name, _ := cals[0].HeaderProperty("X-WR-CALNAME")
+	assert.Equal(t, "Work", name.Value)
+	assert.Equal(t, "2.0", cals[0].Version)
+	assert.Equal(t, "PUBLISH", cals[0].Method)
+	assert.Len(t, cals[0].Properties, 3)
+	assert.Len(t, cals[0].Events, 1)
+	assert.Equal(t, "work-1", cals[0].Events[0].UID)
+
+	name, _ = cals[1].HeaderProperty("X-WR-CALNAME")
+	assert.Equal(t, "Home", name.Value)
+	assert.Empty(t, cals[1].Version)
+	assert.Empty(t, cals[1].Method)
+	assert.Equal(t, []parse.Property{testutil.Property("X-WR-CALNAME", "Home", nil)}, cals[1].Properties)
+	assert.Len(t, cals[1].Events, 1)
+	assert.Equal(t, "home-1", cals[1].Events[0].UID)
+}
+
+func TestItems_xWRTimezone(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+METHOD:PUBLISH
+X-WR-TIMEZONE:Europe/Berlin
+BEGIN:VEVENT
+UID:floating
+DTSTART:20200101T100000
+END:VEVENT
+BEGIN:VEVENT
+UID:zoned
+DTSTART;TZID=America/New_York:20200101T100000
+END:VEVENT
+END:VCALENDAR`
+
+	berlin := testutil.LoadLocation("Europe/Berlin")
+	newYork := testutil.LoadLocation("America/New_York")
+	london := testutil.LoadLocation("Europe/London")
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, time.Date(2020, time.January, 1, 10, 0, 0, 0, berlin), cal.Events[0].Start)
+	assert.Equal(t, time.Date(2020, time.January, 1, 10, 0, 0, 0, newYork), cal.Events[1].Start)
+
+	cal, err = parse.Items(lex.Text(input), parse.FloatingLocation(london))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, time.Date(2020, time.January, 1, 10, 0, 0, 0, london), cal.Events[0].Start)
+
+	cal, err = parse.Items(lex.Text(strings.Replace(input, "Europe/Berlin", "Invalid/Zone", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, time.Date(2020, time.January, 1, 10, 0, 0, 0, time.Local), cal.Events[0].Start)
+}
+
+func TestItems_componentLineWithParams(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND;X-FOO=bar:VEVENT\r\nEND:VEVENT\r\nEND:VCALENDAR"
+
+	_, err := parse.Items(lex.Text(input))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "END line must not have parameters")
+}
+
+func TestFromSlice(t *testing.T) {
+	b, err := os.ReadFile("../lex/testdata/calendar_crlf.ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []lex.Item
+	for item := range lex.Text(string(b)) {
+		items = append(items, item)
+	}
+
+	expected, expectedErr := parse.Slice(items)
+	cal, err := parse.FromSlice(items)
+
+	assert.Equal(t, expectedErr, err)
+	assert.Equal(t, expected, cal)
+	assert.NotEmpty(t, cal.Events)
+
+	_, err = parse.FromSlice(items[:len(items)/2])
+	assert.Error(t, err)
+}
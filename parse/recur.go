@@ -0,0 +1,202 @@
+package parse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is the frequency of a RecurrenceRule.
+type Frequency string
+
+// The recurrence frequencies.
+const (
+	Secondly = Frequency("SECONDLY")
+	Minutely = Frequency("MINUTELY")
+	Hourly   = Frequency("HOURLY")
+	Daily    = Frequency("DAILY")
+	Weekly   = Frequency("WEEKLY")
+	Monthly  = Frequency("MONTHLY")
+	Yearly   = Frequency("YEARLY")
+)
+
+// RecurrenceRule is a parsed recurrence rule (https://tools.ietf.org/html/rfc5545#section-3.3.10).
+type RecurrenceRule struct {
+	Frequency Frequency
+	// Until is the zero Time if the rule has no UNTIL part.
+	Until time.Time
+	// Count is 0 if the rule has no COUNT part.
+	Count int
+	// Interval defaults to 1.
+	Interval   int
+	BySecond   []int
+	ByMinute   []int
+	ByHour     []int
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByYearDay  []int
+	ByWeekNo   []int
+	ByMonth    []int
+	BySetPos   []int
+	// WeekStart defaults to time.Monday.
+	WeekStart time.Weekday
+}
+
+// WeekdayNum is a weekday with an optional ordinal, e.g. "-1SU" (the last sunday).
+type WeekdayNum struct {
+	// N is 0 if the weekday has no ordinal.
+	N       int
+	Weekday time.Weekday
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// parseRecurrenceRule parses the value of a RRULE property. dtstart is the DTSTART
+// property of the component, which determines the timezone of a floating UNTIL value.
+func (p *Parser) parseRecurrenceRule(value string, dtstart Property) (RecurrenceRule, error) {
+	rule := RecurrenceRule{
+		Interval:  1,
+		WeekStart: time.Monday,
+	}
+
+	for _, part := range strings.Split(value, ";") {
+		if part == "" {
+			continue
+		}
+
+		eq := strings.IndexByte(part, '=')
+		if eq < 0 {
+			return rule, fmt.Errorf("missing '=' in rule part %q", part)
+		}
+		name, val := strings.ToUpper(part[:eq]), part[eq+1:]
+
+		var err error
+		switch name {
+		case "FREQ":
+			rule.Frequency, err = parseFrequency(val)
+		case "UNTIL":
+			until := Property{Name: "UNTIL", Value: val}
+			// UNTIL must be UTC if DTSTART has a TZID, so the TZID only
+			// applies to floating UNTIL values.
+			if !strings.HasSuffix(val, "Z") {
+				until.Params = Parameters{"TZID": dtstart.Params["TZID"]}
+			}
+			rule.Until, err = p.parseTime(until)
+		case "COUNT":
+			if rule.Count, err = strconv.Atoi(val); err == nil && rule.Count < 1 {
+				err = fmt.Errorf("count must be positive; got %d", rule.Count)
+			}
+		case "INTERVAL":
+			if rule.Interval, err = strconv.Atoi(val); err == nil && rule.Interval < 1 {
+				err = fmt.Errorf("interval must be positive; got %d", rule.Interval)
+			}
+		case "BYSECOND":
+			rule.BySecond, err = parseIntList(val, 0, 60, false)
+		case "BYMINUTE":
+			rule.ByMinute, err = parseIntList(val, 0, 59, false)
+		case "BYHOUR":
+			rule.ByHour, err = parseIntList(val, 0, 23, false)
+		case "BYDAY":
+			rule.ByDay, err = parseWeekdayNumList(val)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseIntList(val, 1, 31, true)
+		case "BYYEARDAY":
+			rule.ByYearDay, err = parseIntList(val, 1, 366, true)
+		case "BYWEEKNO":
+			rule.ByWeekNo, err = parseIntList(val, 1, 53, true)
+		case "BYMONTH":
+			rule.ByMonth, err = parseIntList(val, 1, 12, false)
+		case "BYSETPOS":
+			rule.BySetPos, err = parseIntList(val, 1, 366, true)
+		case "WKST":
+			var ok bool
+			if rule.WeekStart, ok = weekdays[strings.ToUpper(val)]; !ok {
+				err = fmt.Errorf("invalid weekday %q", val)
+			}
+		}
+
+		if err != nil {
+			return rule, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	if rule.Frequency == "" {
+		return rule, fmt.Errorf("missing FREQ in %q", value)
+	}
+
+	// https://tools.ietf.org/html/rfc5545#section-3.3.10
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return rule, fmt.Errorf("COUNT and UNTIL must not both be set in %q", value)
+	}
+
+	return rule, nil
+}
+
+func parseFrequency(val string) (Frequency, error) {
+	freq := Frequency(strings.ToUpper(val))
+	switch freq {
+	case Secondly, Minutely, Hourly, Daily, Weekly, Monthly, Yearly:
+		return freq, nil
+	default:
+		return "", fmt.Errorf("invalid frequency %q", val)
+	}
+}
+
+// parseIntList parses a comma-separated list of integers in the range [lo, hi].
+// If signed is true, the integers may also be in the range [-hi, -lo].
+func parseIntList(val string, lo, hi int, signed bool) ([]int, error) {
+	parts := strings.Split(val, ",")
+	nums := make([]int, len(parts))
+	for i, part := range parts {
+		num, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+
+		abs := num
+		if signed && abs < 0 {
+			abs = -abs
+		}
+
+		if abs < lo || abs > hi {
+			return nil, fmt.Errorf("%d is out of range", num)
+		}
+
+		nums[i] = num
+	}
+	return nums, nil
+}
+
+func parseWeekdayNumList(val string) ([]WeekdayNum, error) {
+	parts := strings.Split(val, ",")
+	days := make([]WeekdayNum, len(parts))
+	for i, part := range parts {
+		if len(part) < 2 {
+			return nil, fmt.Errorf("invalid weekday %q", part)
+		}
+
+		day, ok := weekdays[strings.ToUpper(part[len(part)-2:])]
+		if !ok {
+			return nil, fmt.Errorf("invalid weekday %q", part)
+		}
+		days[i].Weekday = day
+
+		if ord := part[:len(part)-2]; ord != "" {
+			n, err := strconv.Atoi(ord)
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, fmt.Errorf("invalid weekday ordinal %q", part)
+			}
+			days[i].N = n
+		}
+	}
+	return days, nil
+}
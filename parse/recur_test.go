@@ -0,0 +1,107 @@
+package parse_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bounoable/ical/lex"
+	"github.com/bounoable/ical/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestItems_recurrenceRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		rrule    string
+		expected *parse.RecurrenceRule
+		err      bool
+	}{
+		{
+			name:  "weekly with interval and weekdays",
+			rrule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE",
+			expected: &parse.RecurrenceRule{
+				Frequency: parse.Weekly,
+				Interval:  2,
+				ByDay: []parse.WeekdayNum{
+					{Weekday: time.Monday},
+					{Weekday: time.Wednesday},
+				},
+				WeekStart: time.Monday,
+			},
+		},
+		{
+			name:  "monthly with ordinal weekday and count",
+			rrule: "FREQ=MONTHLY;COUNT=10;BYDAY=-1FR;WKST=SU",
+			expected: &parse.RecurrenceRule{
+				Frequency: parse.Monthly,
+				Count:     10,
+				Interval:  1,
+				ByDay: []parse.WeekdayNum{
+					{N: -1, Weekday: time.Friday},
+				},
+				WeekStart: time.Sunday,
+			},
+		},
+		{
+			name:  "yearly until",
+			rrule: "FREQ=YEARLY;UNTIL=20250101T000000Z;BYMONTH=1,7;BYMONTHDAY=1,-1;BYSETPOS=1",
+			expected: &parse.RecurrenceRule{
+				Frequency:  parse.Yearly,
+				Until:      time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+				Interval:   1,
+				ByMonth:    []int{1, 7},
+				ByMonthDay: []int{1, -1},
+				BySetPos:   []int{1},
+				WeekStart:  time.Monday,
+			},
+		},
+		{
+			name:  "missing frequency",
+			rrule: "INTERVAL=2",
+			err:   true,
+		},
+		{
+			name:  "invalid weekday",
+			rrule: "FREQ=WEEKLY;BYDAY=XX",
+			err:   true,
+		},
+		{
+			name:  "out of range",
+			rrule: "FREQ=DAILY;BYHOUR=24",
+			err:   true,
+		},
+		{
+			name:  "zero count",
+			rrule: "FREQ=DAILY;COUNT=0",
+			err:   true,
+		},
+		{
+			name:  "negative count",
+			rrule: "FREQ=DAILY;COUNT=-3",
+			err:   true,
+		},
+		{
+			name:  "count and until",
+			rrule: "FREQ=DAILY;COUNT=3;UNTIL=20200110T000000Z",
+			err:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := fmt.Sprintf("BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20200101T100000Z\nRRULE:%s\nEND:VEVENT\nEND:VCALENDAR", test.rrule)
+			cal, err := parse.Items(lex.Text(input))
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, test.expected, cal.Events[0].Recurrence)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package parse
+
+import "strings"
+
+// splitText splits a TEXT value with multiple values at the commas that are
+// not escaped by a backslash. The returned values are not unescaped.
+func splitText(val string) []string {
+	return splitEscaped(val, ',')
+}
+
+// splitEscaped splits val at the occurrences of sep that are not escaped
+// by a backslash. The returned values are not unescaped.
+func splitEscaped(val string, sep byte) []string {
+	var vals []string
+	var start int
+	for i := 0; i < len(val); i++ {
+		switch val[i] {
+		case '\\':
+			i++
+		case sep:
+			vals = append(vals, val[start:i])
+			start = i + 1
+		}
+	}
+	return append(vals, val[start:])
+}
+
+// unescapeText unescapes a TEXT value (https://tools.ietf.org/html/rfc5545#section-3.3.11).
+func unescapeText(val string) string {
+	if !strings.Contains(val, `\`) {
+		return val
+	}
+
+	var b strings.Builder
+	b.Grow(len(val))
+	for i := 0; i < len(val); i++ {
+		if val[i] != '\\' || i+1 == len(val) {
+			b.WriteByte(val[i])
+			continue
+		}
+
+		i++
+		switch val[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(val[i])
+		}
+	}
+	return b.String()
+}
+
+// textValue returns the unescaped value of prop if prop has a TEXT value.
+// Values of other types (e.g. URI) are returned as-is, because backslashes
+// are not escape characters in these types.
+func textValue(prop Property) string {
+	if prop.ValueType() != "TEXT" {
+		return prop.Value
+	}
+	return unescapeText(prop.Value)
+}
+
+// localizedText returns the unescaped value of prop with its LANGUAGE parameter.
+func localizedText(prop Property) LocalizedText {
+	lang, _ := prop.Params.First("LANGUAGE")
+	return LocalizedText{Value: textValue(prop), Language: lang}
+}
+
+// isDefaultText reports whether the last of texts replaces the current default
+// text with the language lang: the first text is the default until a text
+// without a language is found.
+func isDefaultText(texts []LocalizedText, lang string) bool {
+	if len(texts) == 1 {
+		return true
+	}
+	return lang != "" && texts[len(texts)-1].Language == ""
+}
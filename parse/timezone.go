@@ -0,0 +1,127 @@
+package parse
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/bounoable/ical/lex"
+)
+
+// location returns the *time.Location for the given TZID. IANA timezone names
+// are loaded from the system's timezone database, all other TZIDs are looked up
+// in the VTIMEZONE components of the calendar.
+func (p *Parser) location(tzid string) (*time.Location, bool) {
+	if loc, err := time.LoadLocation(tzid); err == nil {
+		return loc, true
+	}
+
+	loc, ok := p.tzs[tzid]
+	return loc, ok
+}
+
+// timezones returns the locations of the VTIMEZONE components by TZID.
+// VTIMEZONEs that cannot be converted to a location are ignored.
+func (p *Parser) timezones(components []Component) map[string]*time.Location {
+	var tzs map[string]*time.Location
+	for _, comp := range components {
+		if comp.Name != "VTIMEZONE" {
+			continue
+		}
+
+		if tzurl, ok := comp.Property("TZURL"); ok && p.fetchTZURL != nil {
+			if fetched, err := p.fetchTimezone(tzurl.Value); err == nil {
+				comp = fetched
+			}
+		}
+
+		tzid, ok := comp.Property("TZID")
+		if !ok {
+			continue
+		}
+
+		loc, err := p.timezoneLocation(tzid.Value, comp)
+		if err != nil {
+			continue
+		}
+
+		if tzs == nil {
+			tzs = make(map[string]*time.Location)
+		}
+		tzs[tzid.Value] = loc
+	}
+	return tzs
+}
+
+// fetchTimezone fetches the VTIMEZONE component from the given TZURL.
+func (p *Parser) fetchTimezone(url string) (Component, error) {
+	if comp, ok := p.tzurlCache[url]; ok {
+		return comp, nil
+	}
+
+	rc, err := p.fetchTZURL(url)
+	if err != nil {
+		return Component{}, fmt.Errorf("fetch %s: %w", url, err)
+	}
+	defer rc.Close()
+
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		return Component{}, fmt.Errorf("read %s: %w", url, err)
+	}
+
+	cal, err := NewParser(Context(p.ctx)).parseText(string(b))
+	if err != nil {
+		return Component{}, fmt.Errorf("parse %s: %w", url, err)
+	}
+
+	for _, comp := range cal.Components {
+		if comp.Name == "VTIMEZONE" {
+			if p.tzurlCache == nil {
+				p.tzurlCache = make(map[string]Component)
+			}
+			p.tzurlCache[url] = comp
+			return comp, nil
+		}
+	}
+
+	return Component{}, fmt.Errorf("%s: no VTIMEZONE found", url)
+}
+
+func (p *Parser) parseText(text string) (Calendar, error) {
+	p.Reset(lex.Text(text, lex.Context(p.ctx)))
+	return p.Parse()
+}
+
+// parseUTCOffset parses a UTC-OFFSET value (https://tools.ietf.org/html/rfc5545#section-3.3.14)
+// and returns the offset in seconds.
+//
+// utc-offset = time-numzone
+// time-numzone = ("+" / "-") time-hour time-minute [time-second]
+func parseUTCOffset(val string) (int, error) {
+	if len(val) != 5 && len(val) != 7 {
+		return 0, fmt.Errorf("invalid UTC offset %q", val)
+	}
+
+	var sign int
+	switch val[0] {
+	case '+':
+		sign = 1
+	case '-':
+		sign = -1
+	default:
+		return 0, fmt.Errorf("invalid UTC offset %q", val)
+	}
+
+	var parts [3]int
+	for i := 0; 1+i*2 < len(val); i++ {
+		n, err := strconv.Atoi(val[1+i*2 : 3+i*2])
+		if err != nil || n < 0 || n > 59 {
+			return 0, fmt.Errorf("invalid UTC offset %q", val)
+		}
+		parts[i] = n
+	}
+
+	return sign * (parts[0]*3600 + parts[1]*60 + parts[2]), nil
+}
@@ -0,0 +1,277 @@
+package parse_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bounoable/ical/internal/testutil"
+	"github.com/bounoable/ical/lex"
+	"github.com/bounoable/ical/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+const customTimezoneCalendar = `BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:1
+DTSTART;TZID=Custom Standard Time:20200101T100000
+END:VEVENT
+BEGIN:VTIMEZONE
+TZID:Custom Standard Time
+TZURL:https://example.com/tz/custom.ics
+BEGIN:STANDARD
+DTSTART:19700101T000000
+TZOFFSETFROM:+0530
+TZOFFSETTO:+0530
+END:STANDARD
+END:VTIMEZONE
+END:VCALENDAR`
+
+const fetchedTimezoneCalendar = `BEGIN:VCALENDAR
+BEGIN:VTIMEZONE
+TZID:Custom Standard Time
+BEGIN:STANDARD
+DTSTART:19700101T000000
+TZOFFSETFROM:+0200
+TZOFFSETTO:+0200
+END:STANDARD
+END:VTIMEZONE
+END:VCALENDAR`
+
+func TestItems_inlineTimezone(t *testing.T) {
+	cal, err := parse.Items(lex.Text(customTimezoneCalendar))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, cal.Components, 1)
+	assert.Equal(t, "VTIMEZONE", cal.Components[0].Name)
+	assert.Len(t, cal.Components[0].Components, 1)
+	assert.Equal(t, "STANDARD", cal.Components[0].Components[0].Name)
+	assert.Contains(t, cal.Timezones, "Custom Standard Time")
+
+	start := cal.Events[0].Start
+	assert.Equal(t, time.Date(2020, time.January, 1, 4, 30, 0, 0, time.UTC), start.UTC())
+	_, offset := start.Zone()
+	assert.Equal(t, 5*60*60+30*60, offset)
+}
+
+func TestItems_inlineTimezone_latestStandard(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VTIMEZONE
+TZID:Custom
+BEGIN:DAYLIGHT
+DTSTART:19810329T020000
+TZOFFSETFROM:+0100
+TZOFFSETTO:+0200
+END:DAYLIGHT
+BEGIN:STANDARD
+DTSTART:19961027T030000
+TZOFFSETFROM:+0200
+TZOFFSETTO:+0100
+END:STANDARD
+BEGIN:STANDARD
+DTSTART:19701025T030000
+TZOFFSETFROM:+0200
+TZOFFSETTO:+0000
+END:STANDARD
+END:VTIMEZONE
+BEGIN:VEVENT
+DTSTART;TZID=Custom:20200101T100000
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, time.Date(2020, time.January, 1, 9, 0, 0, 0, time.UTC), cal.Events[0].Start.UTC())
+}
+
+func TestTZURLFetcher(t *testing.T) {
+	var fetched []string
+	p := parse.NewParser(parse.TZURLFetcher(func(url string) (io.ReadCloser, error) {
+		fetched = append(fetched, url)
+		return io.NopCloser(strings.NewReader(fetchedTimezoneCalendar)), nil
+	}))
+
+	for i := 0; i < 2; i++ {
+		p.Reset(lex.Text(customTimezoneCalendar))
+		cal, err := p.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, time.Date(2020, time.January, 1, 8, 0, 0, 0, time.UTC), cal.Events[0].Start.UTC())
+	}
+
+	assert.Equal(t, []string{"https://example.com/tz/custom.ics"}, fetched, "fetched definitions should be cached")
+}
+
+func TestTZURLFetcher_error(t *testing.T) {
+	cal, err := parse.Items(lex.Text(customTimezoneCalendar), parse.TZURLFetcher(func(string) (io.ReadCloser, error) {
+		return nil, errors.New("offline")
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, time.Date(2020, time.January, 1, 4, 30, 0, 0, time.UTC), cal.Events[0].Start.UTC())
+}
+
+func TestItems_timezoneTransitions(t *testing.T) {
+	tests := []struct {
+		name     string
+		vtz      string
+		compare  *time.Location
+		from, to time.Time
+	}{
+		{
+			name: "central europe",
+			vtz: `BEGIN:VTIMEZONE
+TZID:Custom Berlin
+BEGIN:DAYLIGHT
+TZNAME:CEST
+DTSTART:19810329T020000
+TZOFFSETFROM:+0100
+TZOFFSETTO:+0200
+RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU
+END:DAYLIGHT
+BEGIN:STANDARD
+TZNAME:CET
+DTSTART:19961027T030000
+TZOFFSETFROM:+0200
+TZOFFSETTO:+0100
+RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU
+END:STANDARD
+END:VTIMEZONE`,
+			compare: testutil.LoadLocation("Europe/Berlin"),
+			from:    time.Date(1997, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:      time.Date(2100, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "southern hemisphere",
+			vtz: `BEGIN:VTIMEZONE
+TZID:Custom Sydney
+BEGIN:STANDARD
+DTSTART:20080406T030000
+TZOFFSETFROM:+1100
+TZOFFSETTO:+1000
+RRULE:FREQ=YEARLY;BYMONTH=4;BYDAY=1SU
+END:STANDARD
+BEGIN:DAYLIGHT
+DTSTART:20081005T020000
+TZOFFSETFROM:+1000
+TZOFFSETTO:+1100
+RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=1SU
+END:DAYLIGHT
+END:VTIMEZONE`,
+			compare: testutil.LoadLocation("Australia/Sydney"),
+			from:    time.Date(2009, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:      time.Date(2100, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "historical & explicit rules",
+			vtz: `BEGIN:VTIMEZONE
+TZID:Custom New York
+BEGIN:DAYLIGHT
+DTSTART:19870405T020000
+TZOFFSETFROM:-0500
+TZOFFSETTO:-0400
+RRULE:FREQ=YEARLY;BYMONTH=4;BYDAY=1SU;UNTIL=20060402T070000Z
+END:DAYLIGHT
+BEGIN:STANDARD
+DTSTART:19671029T020000
+TZOFFSETFROM:-0400
+TZOFFSETTO:-0500
+RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU;UNTIL=20061029T060000Z
+END:STANDARD
+BEGIN:DAYLIGHT
+DTSTART:20070311T020000
+TZOFFSETFROM:-0500
+TZOFFSETTO:-0400
+RRULE:FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=8,9,10,11,12,13,14;BYDAY=SU
+END:DAYLIGHT
+BEGIN:STANDARD
+DTSTART:20071104T020000
+TZOFFSETFROM:-0400
+TZOFFSETTO:-0500
+RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU
+END:STANDARD
+END:VTIMEZONE`,
+			compare: testutil.LoadLocation("America/New_York"),
+			from:    time.Date(1988, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:      time.Date(2100, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cal, err := parse.Items(lex.Text("BEGIN:VCALENDAR\n" + test.vtz + "\nEND:VCALENDAR"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var loc *time.Location
+			for _, l := range cal.Timezones {
+				loc = l
+			}
+			if !assert.NotNil(t, loc) {
+				return
+			}
+
+			for at := test.from; at.Before(test.to); at = at.Add(17 * time.Hour) {
+				_, offset := at.In(loc).Zone()
+				_, expected := at.In(test.compare).Zone()
+				if offset != expected {
+					t.Fatalf("offset at %v: expected %d; got %d", at, expected, offset)
+				}
+			}
+		})
+	}
+}
+
+func TestItems_timezoneSpringForward(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VTIMEZONE
+TZID:Custom Berlin
+BEGIN:DAYLIGHT
+TZNAME:CEST
+DTSTART:19810329T020000
+TZOFFSETFROM:+0100
+TZOFFSETTO:+0200
+RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU
+END:DAYLIGHT
+BEGIN:STANDARD
+TZNAME:CET
+DTSTART:19961027T030000
+TZOFFSETFROM:+0200
+TZOFFSETTO:+0100
+RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU
+END:STANDARD
+END:VTIMEZONE
+BEGIN:VEVENT
+DTSTART;TZID=Custom Berlin:20200329T015959
+DTEND;TZID=Custom Berlin:20200329T030000
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evt := cal.Events[0]
+	name, offset := evt.Start.Zone()
+	assert.Equal(t, "CET", name)
+	assert.Equal(t, 60*60, offset)
+	assert.Equal(t, time.Date(2020, time.March, 29, 0, 59, 59, 0, time.UTC), evt.Start.UTC())
+
+	name, offset = evt.End.Zone()
+	assert.Equal(t, "CEST", name)
+	assert.Equal(t, 2*60*60, offset)
+	assert.Equal(t, time.Date(2020, time.March, 29, 1, 0, 0, 0, time.UTC), evt.End.UTC())
+}
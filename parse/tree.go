@@ -0,0 +1,74 @@
+package parse
+
+// ComponentNode is a component of a calendar in a uniform tree of components
+// and properties, e.g. for template engines or serializers that walk a calendar
+// without knowing its typed fields.
+type ComponentNode struct {
+	Name       string
+	Properties []Property
+	Children   []ComponentNode
+}
+
+// Tree returns the calendar as a tree of components. The root node is the
+// VCALENDAR, whose children are the components that have no dedicated type
+// (e.g. VTIMEZONEs), followed by the events. The children of an event are its
+// alarms, followed by its components.
+func (cal Calendar) Tree() ComponentNode {
+	root := ComponentNode{
+		Name:       "VCALENDAR",
+		Properties: cal.Properties,
+	}
+
+	for _, comp := range cal.Components {
+		root.Children = append(root.Children, comp.node())
+	}
+
+	for _, evt := range cal.Events {
+		root.Children = append(root.Children, evt.node())
+	}
+
+	return root
+}
+
+func (evt Event) node() ComponentNode {
+	node := ComponentNode{
+		Name:       "VEVENT",
+		Properties: evt.Properties,
+	}
+
+	for _, alarm := range evt.Alarms {
+		node.Children = append(node.Children, alarm.node())
+	}
+
+	for _, comp := range evt.Components {
+		node.Children = append(node.Children, comp.node())
+	}
+
+	return node
+}
+
+func (alarm Alarm) node() ComponentNode {
+	node := ComponentNode{
+		Name:       "VALARM",
+		Properties: alarm.Properties,
+	}
+
+	for _, comp := range alarm.Components {
+		node.Children = append(node.Children, comp.node())
+	}
+
+	return node
+}
+
+func (comp Component) node() ComponentNode {
+	node := ComponentNode{
+		Name:       comp.Name,
+		Properties: comp.Properties,
+	}
+
+	for _, sub := range comp.Components {
+		node.Children = append(node.Children, sub.node())
+	}
+
+	return node
+}
@@ -0,0 +1,48 @@
+package parse_test
+
+import (
+	"testing"
+
+	"github.com/bounoable/ical/internal/testutil"
+	"github.com/bounoable/ical/lex"
+	"github.com/bounoable/ical/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalendar_Tree(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:1
+SUMMARY:Meeting
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER:-PT15M
+END:VALARM
+END:VEVENT
+END:VCALENDAR`
+
+	cal, err := parse.Items(lex.Text(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, parse.ComponentNode{
+		Name:       "VCALENDAR",
+		Properties: []parse.Property{testutil.Property("VERSION", "2.0", nil)},
+		Children: []parse.ComponentNode{{
+			Name: "VEVENT",
+			Properties: []parse.Property{
+				testutil.Property("UID", "1", nil),
+				testutil.Property("SUMMARY", "Meeting", nil),
+			},
+			Children: []parse.ComponentNode{{
+				Name: "VALARM",
+				Properties: []parse.Property{
+					testutil.Property("ACTION", "DISPLAY", nil),
+					testutil.Property("TRIGGER", "-PT15M", nil),
+				},
+			}},
+		}},
+	}, cal.Tree())
+}
@@ -0,0 +1,337 @@
+package parse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxTransitionYear is the year up to which the transitions of recurring
+// observances are computed, if they cannot be described by a POSIX TZ string.
+const maxTransitionYear = 2100
+
+// observance is a STANDARD or DAYLIGHT sub-component of a VTIMEZONE
+// (https://tools.ietf.org/html/rfc5545#section-3.6.5).
+type observance struct {
+	name       string
+	isDST      bool
+	offsetFrom int
+	offsetTo   int
+	// start is the local time of the first onset, as a UTC time
+	start  time.Time
+	rule   *RecurrenceRule
+	rdates []time.Time
+}
+
+type transition struct {
+	at  time.Time
+	obs *observance
+}
+
+// timezoneLocation builds a location from the observances of the VTIMEZONE
+// component. The transitions between the observances are converted to TZif
+// data (RFC 8536) that is loaded with time.LoadLocationFromTZData.
+func (p *Parser) timezoneLocation(tzid string, comp Component) (*time.Location, error) {
+	observances, err := p.observances(comp)
+	if err != nil {
+		return nil, fmt.Errorf("VTIMEZONE %s: %w", tzid, err)
+	}
+
+	if len(observances) == 0 {
+		return nil, fmt.Errorf("VTIMEZONE %s has no observances", tzid)
+	}
+
+	footer, lastYear := posixTZ(observances)
+
+	var transitions []transition
+	for _, obs := range observances {
+		onsets := append([]time.Time{obs.start}, obs.rdates...)
+		if obs.rule != nil {
+			it := newRuleIterator(*obs.rule, obs.start)
+			for t, ok := it.next(); ok && t.Year() <= lastYear; t, ok = it.next() {
+				onsets = append(onsets, t)
+			}
+		}
+
+		for _, onset := range onsets {
+			transitions = append(transitions, transition{
+				at:  onset.Add(-time.Duration(obs.offsetFrom) * time.Second),
+				obs: obs,
+			})
+		}
+	}
+
+	sort.SliceStable(transitions, func(a, b int) bool { return transitions[a].at.Before(transitions[b].at) })
+
+	return time.LoadLocationFromTZData(tzid, tzif(observances, transitions, footer))
+}
+
+func (p *Parser) observances(comp Component) ([]*observance, error) {
+	var observances []*observance
+	for _, sub := range comp.Components {
+		if sub.Name != "STANDARD" && sub.Name != "DAYLIGHT" {
+			continue
+		}
+
+		obs := observance{isDST: sub.Name == "DAYLIGHT"}
+
+		for _, prop := range sub.Properties {
+			var err error
+			switch prop.Name {
+			case "TZNAME":
+				if obs.name == "" {
+					obs.name = prop.Value
+				}
+			case "TZOFFSETFROM":
+				obs.offsetFrom, err = parseUTCOffset(prop.Value)
+			case "TZOFFSETTO":
+				obs.offsetTo, err = parseUTCOffset(prop.Value)
+			case "DTSTART":
+				obs.start, err = time.Parse(layoutDateTimeLocal, prop.Value)
+			case "RDATE":
+				var rdates []time.Time
+				if rdates, err = p.parseTimeList(utcProperty(prop)); err == nil {
+					obs.rdates = append(obs.rdates, rdates...)
+				}
+			case "RRULE":
+				var rule RecurrenceRule
+				if rule, err = p.parseRecurrenceRule(prop.Value, utcProperty(prop)); err == nil {
+					obs.rule = &rule
+				}
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", sub.Name, prop.Name, err)
+			}
+		}
+
+		if _, ok := sub.Property("DTSTART"); !ok {
+			return nil, fmt.Errorf("%s: missing DTSTART", sub.Name)
+		}
+
+		if _, ok := sub.Property("TZOFFSETTO"); !ok {
+			return nil, fmt.Errorf("%s: missing TZOFFSETTO", sub.Name)
+		}
+
+		if !isAbbreviation(obs.name) {
+			obs.name = formatUTCOffset(obs.offsetTo)
+		}
+
+		observances = append(observances, &obs)
+	}
+	return observances, nil
+}
+
+// utcProperty returns prop with the TZID parameter set to UTC, so that the
+// local times of an observance are parsed as UTC times.
+func utcProperty(prop Property) Property {
+	params := prop.Params.clone()
+	if params == nil {
+		params = make(Parameters)
+	}
+	params["TZID"] = []string{"UTC"}
+	prop.Params = params
+	return prop
+}
+
+// posixTZ returns the POSIX TZ string (https://pubs.opengroup.org/onlinepubs/9699919799/basedefs/V1_chap08.html)
+// that describes the transitions after the last year of the observances,
+// and the last year whose transitions must be computed explicitly.
+// If the recurring observances cannot be described by a POSIX TZ string,
+// the returned string is empty and the last year is maxTransitionYear.
+func posixTZ(observances []*observance) (string, int) {
+	var std, dst *observance
+	lastYear := 0
+	for _, obs := range observances {
+		if obs.start.Year() > lastYear {
+			lastYear = obs.start.Year()
+		}
+
+		if obs.rule == nil {
+			continue
+		}
+
+		if obs.rule.Count > 0 || !obs.rule.Until.IsZero() {
+			if obs.rule.Until.Year() > lastYear {
+				lastYear = obs.rule.Until.Year()
+			}
+			continue
+		}
+
+		if obs.isDST && dst == nil {
+			dst = obs
+		} else if !obs.isDST && std == nil {
+			std = obs
+		} else {
+			return "", maxTransitionYear
+		}
+	}
+	lastYear++
+
+	if std == nil && dst == nil {
+		// all transitions are computed explicitly
+		return "", lastYear
+	}
+
+	if std == nil || dst == nil {
+		return "", maxTransitionYear
+	}
+
+	dstRule, ok := posixRule(dst)
+	if !ok {
+		return "", maxTransitionYear
+	}
+
+	stdRule, ok := posixRule(std)
+	if !ok {
+		return "", maxTransitionYear
+	}
+
+	return fmt.Sprintf(
+		"<%s>%s<%s>%s,%s,%s",
+		std.name, posixOffset(std.offsetTo),
+		dst.name, posixOffset(dst.offsetTo),
+		dstRule, stdRule,
+	), lastYear
+}
+
+// posixRule returns the "Mm.w.d/time" rule of a yearly recurring observance.
+func posixRule(obs *observance) (string, bool) {
+	rule := obs.rule
+	if rule.Frequency != Yearly || rule.Interval != 1 ||
+		len(rule.ByMonth) != 1 || len(rule.ByDay) != 1 ||
+		len(rule.ByMonthDay) > 0 || len(rule.ByYearDay) > 0 || len(rule.ByWeekNo) > 0 ||
+		len(rule.BySetPos) > 0 || len(rule.ByHour) > 0 || len(rule.ByMinute) > 0 || len(rule.BySecond) > 0 {
+		return "", false
+	}
+
+	week := rule.ByDay[0].N
+	switch {
+	case week == -1:
+		week = 5
+	case week < 1 || week > 4:
+		return "", false
+	}
+
+	h, m, s := obs.start.Clock()
+	return fmt.Sprintf("M%d.%d.%d/%d:%02d:%02d", rule.ByMonth[0], week, rule.ByDay[0].Weekday, h, m, s), true
+}
+
+// posixOffset formats a UTC offset for a POSIX TZ string, which has the inverse sign.
+func posixOffset(offset int) string {
+	sign := "-"
+	if offset <= 0 {
+		sign = ""
+		offset = -offset
+	}
+	return fmt.Sprintf("%s%d:%02d:%02d", sign, offset/3600, offset/60%60, offset%60)
+}
+
+// tzif encodes the transitions as version 2 TZif data.
+func tzif(observances []*observance, transitions []transition, footer string) []byte {
+	type zone struct {
+		offset int
+		isDST  bool
+		name   string
+	}
+
+	var zones []zone
+	var abbrevs strings.Builder
+	abbrevIndex := make(map[string]int)
+	zoneIndex := func(z zone) int {
+		for i, existing := range zones {
+			if existing == z {
+				return i
+			}
+		}
+		if _, ok := abbrevIndex[z.name]; !ok {
+			abbrevIndex[z.name] = abbrevs.Len()
+			abbrevs.WriteString(z.name + "\x00")
+		}
+		zones = append(zones, z)
+		return len(zones) - 1
+	}
+
+	// the zone before the first transition is the local time type 0
+	initial := zone{offset: observances[0].offsetTo, isDST: observances[0].isDST, name: observances[0].name}
+	if len(transitions) > 0 {
+		first := transitions[0].obs
+		initial = zone{offset: first.offsetFrom, name: formatUTCOffset(first.offsetFrom)}
+		for _, obs := range observances {
+			if obs.offsetTo == first.offsetFrom {
+				initial = zone{offset: obs.offsetTo, isDST: obs.isDST, name: obs.name}
+				break
+			}
+		}
+	}
+	zoneIndex(initial)
+
+	indices := make([]byte, len(transitions))
+	for i, tr := range transitions {
+		indices[i] = byte(zoneIndex(zone{offset: tr.obs.offsetTo, isDST: tr.obs.isDST, name: tr.obs.name}))
+	}
+
+	var buf bytes.Buffer
+	write := func(v interface{}) { binary.Write(&buf, binary.BigEndian, v) }
+
+	// version 1 header without data, which is skipped by readers of version 2 data
+	buf.WriteString("TZif2")
+	buf.Write(make([]byte, 15))
+	write([6]uint32{})
+
+	buf.WriteString("TZif2")
+	buf.Write(make([]byte, 15))
+	write([6]uint32{0, 0, 0, uint32(len(transitions)), uint32(len(zones)), uint32(abbrevs.Len())})
+
+	for _, tr := range transitions {
+		write(tr.at.Unix())
+	}
+	buf.Write(indices)
+
+	for _, z := range zones {
+		write(int32(z.offset))
+		if z.isDST {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+		buf.WriteByte(byte(abbrevIndex[z.name]))
+	}
+	buf.WriteString(abbrevs.String())
+
+	buf.WriteString("\n" + footer + "\n")
+
+	return buf.Bytes()
+}
+
+// formatUTCOffset formats an offset in seconds as a UTC-OFFSET value, e.g. "+0530".
+func formatUTCOffset(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+
+	if offset%60 != 0 {
+		return fmt.Sprintf("%c%02d%02d%02d", sign, offset/3600, offset/60%60, offset%60)
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, offset/3600, offset/60%60)
+}
+
+// isAbbreviation determines if name can be used as a timezone abbreviation
+// in a POSIX TZ string.
+func isAbbreviation(name string) bool {
+	if len(name) < 3 {
+		return false
+	}
+
+	for _, r := range name {
+		if !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '+' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package ical
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// Repair returns a Reader that normalizes common malformations of the iCalendar
+// in r, so that it can be parsed by the strict lexer:
+//
+//   - LF line endings are replaced with CRLF
+//   - blank lines are removed
+//   - trailing whitespace is trimmed from lines that are not folded
+//   - the component names of BEGIN and END lines are upper-cased
+func Repair(r io.Reader) io.Reader {
+	return &repairReader{r: bufio.NewReader(r)}
+}
+
+type repairReader struct {
+	r   *bufio.Reader
+	buf bytes.Buffer
+	// prev is the previous line, which is buffered until it is known
+	// whether the following line continues it.
+	prev    string
+	hasPrev bool
+	err     error
+}
+
+func (rr *repairReader) Read(p []byte) (int, error) {
+	for rr.buf.Len() == 0 && rr.err == nil {
+		rr.next()
+	}
+
+	if rr.buf.Len() > 0 {
+		return rr.buf.Read(p)
+	}
+
+	return 0, rr.err
+}
+
+func (rr *repairReader) next() {
+	line, err := rr.r.ReadString('\n')
+	line = strings.TrimRight(line, "\r\n")
+
+	if strings.TrimSpace(line) != "" {
+		if rr.hasPrev {
+			rr.flush(!isContinuation(line))
+		}
+		rr.prev, rr.hasPrev = line, true
+	}
+
+	if err != nil {
+		if rr.hasPrev {
+			rr.flush(true)
+		}
+		rr.err = err
+	}
+}
+
+// flush writes the buffered line. Trailing whitespace is only trimmed from the
+// line if it is not continued by the next line, because the whitespace is part
+// of the value of folded lines.
+func (rr *repairReader) flush(trim bool) {
+	line := rr.prev
+	if trim {
+		line = strings.TrimRight(line, " \t")
+	}
+
+	rr.buf.WriteString(repairComponentLine(line))
+	rr.buf.WriteString("\r\n")
+	rr.hasPrev = false
+}
+
+func isContinuation(line string) bool {
+	return line[0] == ' ' || line[0] == '\t'
+}
+
+func repairComponentLine(line string) string {
+	colon := strings.IndexByte(line, ':')
+	if colon < 0 {
+		return line
+	}
+
+	switch strings.ToUpper(line[:colon]) {
+	case "BEGIN", "END":
+		return strings.ToUpper(strings.TrimSpace(line))
+	default:
+		return line
+	}
+}
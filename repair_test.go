@@ -0,0 +1,56 @@
+package ical_test
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/bounoable/ical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepair(t *testing.T) {
+	f, err := os.Open("testdata/messy.ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	repaired, err := io.ReadAll(ical.Repair(f))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "BEGIN:VCALENDAR\r\n"+
+		"VERSION:2.0\r\n"+
+		"PRODID:-//Example//Messy//EN\r\n"+
+		"BEGIN:VEVENT\r\n"+
+		"UID:messy-1\r\n"+
+		"DTSTART:20200101T100000Z\r\n"+
+		"DTEND:20200101T110000Z\r\n"+
+		"SUMMARY:Messy \r\n"+
+		" event\r\n"+
+		"END:VEVENT\r\n"+
+		"END:VCALENDAR\r\n", string(repaired))
+}
+
+func TestRepair_parse(t *testing.T) {
+	_, err := ical.ParseFile("testdata/messy.ics")
+	assert.Error(t, err)
+
+	f, err := os.Open("testdata/messy.ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	cal, err := ical.Parse(ical.Repair(f))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "2.0", cal.Version)
+	assert.Len(t, cal.Events, 1)
+	assert.Equal(t, "messy-1", cal.Events[0].UID)
+	assert.Equal(t, "Messy event", cal.Events[0].Summary)
+}